@@ -26,7 +26,12 @@ func main() {
 	logger.Init(cfg.LogLevel, cfg.Debug)
 	log.Info().Msg("Starting Spotify Cover Bot")
 
-	spotifyClient := spotify.NewClient(cfg.SpotifyClientID, cfg.SpotifyClientSecret)
+	spotifyClient := spotify.NewClient(
+		cfg.SpotifyClientID,
+		cfg.SpotifyClientSecret,
+		cfg.SpotifyPageConcurrency,
+		cfg.SpotifyMarket,
+	)
 
 	// Автоматическая инициализация auth для playlist
 	var playlistManager *spotify.PlaylistManager
@@ -63,10 +68,17 @@ func main() {
 	log.Info().
 		Int("workers", cfg.WorkerPoolSize).
 		Int("max_concurrent", cfg.MaxConcurrentDownloads).
+		Int("page_concurrency", cfg.SpotifyPageConcurrency).
+		Str("market", cfg.SpotifyMarket).
 		Dur("image_timeout", cfg.ImageDownloadTimeout).
 		Dur("process_timeout", cfg.ProcessTimeout).
 		Int("max_album_size", cfg.MaxAlbumSize).
 		Int("max_file_size_mb", cfg.MaxFileSizeMB).
+		Str("file_id_cache", cfg.FileIDCacheFile).
+		Int("admins", len(cfg.AdminUserIDs)).
+		Str("default_resolution", string(cfg.DefaultResolution)).
+		Bool("captions", cfg.CaptionsEnabled).
+		Str("delivery", cfg.DefaultDelivery).
 		Bool("debug", cfg.Debug).
 		Bool("auto_playlist", cfg.EnableAutoPlaylist).
 		Msg("Configuration loaded")
@@ -5,6 +5,7 @@ go 1.25.1
 require (
 	github.com/joho/godotenv v1.5.1
 	github.com/rs/zerolog v1.34.0
+	golang.org/x/image v0.25.0
 	gopkg.in/telebot.v4 v4.0.0-beta.5
 )
 
@@ -12,4 +13,5 @@ require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
 	golang.org/x/sys v0.12.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )
@@ -6,19 +6,24 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"image2spotify/internal/spotify"
 )
 
 type Config struct {
 	// Telegram (Primary bot)
 	TelegramBotToken string
-	
+
 	// Worker bots (for uploading to channel)
 	WorkerBotTokens []string
 	LogChannelID    int64
+	AdminUserIDs    []int64 // users allowed to run maintenance commands such as /reindex
 
 	// Spotify
-	SpotifyClientID     string
-	SpotifyClientSecret string
+	SpotifyClientID        string
+	SpotifyClientSecret    string
+	SpotifyPageConcurrency int    // parallel page requests per playlist/album
+	SpotifyMarket          string // default market (ISO country code), empty for none
 
 	// Workers
 	WorkerPoolSize         int
@@ -27,9 +32,19 @@ type Config struct {
 	ProcessTimeout         time.Duration
 
 	// Telegram Limits
-	MaxAlbumSize          int
-	MaxFileSizeMB         int
-	MaxMessagesPerSecond  int
+	MaxAlbumSize         int
+	MaxFileSizeMB        int
+	MaxMessagesPerSecond int
+	FileIDCacheFile      string // file_id of covers already sent, empty to disable
+
+	// Covers
+	DefaultResolution spotify.Resolution // cover size for users without their own setting
+	UserSettingsFile  string
+	CaptionsEnabled   bool   // caption covers for users without their own setting
+	CaptionTemplate   string // template for "/caption on", empty for the built-in one
+	CaptionParseMode  string // HTML, MarkdownV2 or none
+	DefaultDelivery   string // photo, album, document, archive, collage, palette or slideshow
+	FilenameTemplate  string // names of covers sent as files
 
 	// Inline Mode
 	InlineCacheTime  int
@@ -39,9 +54,9 @@ type Config struct {
 	Debug    bool
 	LogLevel string
 
-	AutoPlaylistID          string // ID плейлиста для автозаполнения
-	SpotifyRefreshToken     string // Refresh token для OAuth
-	EnableAutoPlaylist      bool   // Включить автоплейлист
+	AutoPlaylistID      string // ID плейлиста для автозаполнения
+	SpotifyRefreshToken string // Refresh token для OAuth
+	EnableAutoPlaylist  bool   // Включить автоплейлист
 }
 
 func Load() *Config {
@@ -50,6 +65,8 @@ func Load() *Config {
 		LogChannelID:           getEnvInt64OrDefault("LOG_CHANNEL_ID", -1003065136240),
 		SpotifyClientID:        os.Getenv("SPOTIFY_CLIENT_ID"),
 		SpotifyClientSecret:    os.Getenv("SPOTIFY_CLIENT_SECRET"),
+		SpotifyPageConcurrency: getEnvIntOrDefault("SPOTIFY_PAGE_CONCURRENCY", 8),
+		SpotifyMarket:          strings.TrimSpace(os.Getenv("SPOTIFY_MARKET")),
 		WorkerPoolSize:         getEnvIntOrDefault("WORKER_POOL_SIZE", 100),
 		MaxConcurrentDownloads: getEnvIntOrDefault("MAX_CONCURRENT_DOWNLOADS", 50),
 		ImageDownloadTimeout:   time.Duration(getEnvIntOrDefault("IMAGE_DOWNLOAD_TIMEOUT_SEC", 15)) * time.Second,
@@ -57,6 +74,12 @@ func Load() *Config {
 		MaxAlbumSize:           getEnvIntOrDefault("MAX_ALBUM_SIZE", 10),
 		MaxFileSizeMB:          getEnvIntOrDefault("MAX_FILE_SIZE_MB", 20),
 		MaxMessagesPerSecond:   getEnvIntOrDefault("MAX_MESSAGES_PER_SECOND", 15),
+		UserSettingsFile:       getEnvOrDefault("USER_SETTINGS_FILE", "user_settings.json"),
+		CaptionsEnabled:        getEnvBoolOrDefault("CAPTIONS_ENABLED", false),
+		CaptionTemplate:        strings.ReplaceAll(os.Getenv("CAPTION_TEMPLATE"), `\n`, "\n"),
+		CaptionParseMode:       getEnvOrDefault("CAPTION_PARSE_MODE", "HTML"),
+		DefaultDelivery:        getEnvOrDefault("DEFAULT_DELIVERY", "photo"),
+		FilenameTemplate:       getEnvOrDefault("FILENAME_TEMPLATE", "{artist} - {album} ({year}).jpg"),
 		InlineCacheTime:        getEnvIntOrDefault("INLINE_CACHE_TIME", 300),
 		MaxInlineResults:       getEnvIntOrDefault("MAX_INLINE_RESULTS", 50),
 		Debug:                  getEnvBoolOrDefault("DEBUG", false),
@@ -67,6 +90,24 @@ func Load() *Config {
 		EnableAutoPlaylist:  getEnvBoolOrDefault("ENABLE_AUTO_PLAYLIST", false),
 	}
 
+	cfg.DefaultResolution = spotify.ResolutionLargest
+	if value := os.Getenv("DEFAULT_RESOLUTION"); value != "" {
+		if res, ok := spotify.ParseResolution(value); ok {
+			cfg.DefaultResolution = res
+		}
+	}
+
+	// Invalid markets are kept as given and rejected by Validate
+	if market, ok := spotify.ParseMarket(cfg.SpotifyMarket); ok {
+		cfg.SpotifyMarket = market
+	}
+
+	// An empty FILE_ID_CACHE_FILE turns the cache off, so unset and empty differ
+	cfg.FileIDCacheFile = "file_id_cache.jsonl"
+	if value, ok := os.LookupEnv("FILE_ID_CACHE_FILE"); ok {
+		cfg.FileIDCacheFile = strings.TrimSpace(value)
+	}
+
 	// Load worker bot tokens
 	workerTokensStr := os.Getenv("WORKER_BOT_TOKENS")
 	if workerTokensStr != "" {
@@ -76,6 +117,13 @@ func Load() *Config {
 		}
 	}
 
+	// Load admin user IDs
+	for _, value := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			cfg.AdminUserIDs = append(cfg.AdminUserIDs, id)
+		}
+	}
+
 	return cfg
 }
 
@@ -86,6 +134,9 @@ func (c *Config) Validate() error {
 	if c.SpotifyClientID == "" || c.SpotifyClientSecret == "" {
 		return fmt.Errorf("SPOTIFY_CLIENT_ID and SPOTIFY_CLIENT_SECRET are required")
 	}
+	if _, ok := spotify.ParseMarket(c.SpotifyMarket); c.SpotifyMarket != "" && !ok {
+		return fmt.Errorf("SPOTIFY_MARKET %q is not a two-letter country code such as US or JP", c.SpotifyMarket)
+	}
 	return nil
 }
 
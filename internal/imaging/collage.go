@@ -0,0 +1,38 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Background of empty collage cells
+var collageBackground = color.RGBA{R: 18, G: 18, B: 18, A: 255}
+
+// GridFor returns an almost square grid that fits n covers, for posters.
+func GridFor(n int) (cols, rows int) {
+	if n <= 0 {
+		return 1, 1
+	}
+	cols = int(math.Ceil(math.Sqrt(float64(n))))
+	rows = (n + cols - 1) / cols
+	return cols, rows
+}
+
+// Collage draws the covers row by row into a cols×rows grid of square tiles,
+// width pixels wide. Covers beyond the grid are ignored.
+func Collage(covers []*image.RGBA, cols, rows, width int) *image.RGBA {
+	tile := max(width/cols, 1)
+	dst := image.NewRGBA(image.Rect(0, 0, tile*cols, tile*rows))
+	draw.Draw(dst, dst.Rect, image.NewUniform(collageBackground), image.Point{}, draw.Src)
+
+	for i, cover := range covers {
+		if i >= cols*rows {
+			break
+		}
+		x, y := (i%cols)*tile, (i/cols)*tile
+		draw.Draw(dst, image.Rect(x, y, x+tile, y+tile), Fill(cover, tile, tile), image.Point{}, draw.Src)
+	}
+	return dst
+}
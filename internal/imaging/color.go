@@ -0,0 +1,53 @@
+package imaging
+
+import (
+	"image/color"
+	"math"
+)
+
+// HSL converts a color to hue (0-360), saturation and lightness (0-1).
+func HSL(c color.RGBA) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC, minC := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l = (maxC + minC) / 2
+	d := maxC - minC
+	if d == 0 {
+		return 0, 0, l
+	}
+
+	if l > 0.5 {
+		s = d / (2 - maxC - minC)
+	} else {
+		s = d / (maxC + minC)
+	}
+	switch maxC {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, l
+}
+
+// ColorLess orders colors along the rainbow; greys go last, light to dark.
+func ColorLess(a, b color.RGBA) bool {
+	ha, sa, la := HSL(a)
+	hb, sb, lb := HSL(b)
+	greyA, greyB := sa < 0.15, sb < 0.15
+	if greyA != greyB {
+		return greyB
+	}
+	if greyA {
+		return la > lb
+	}
+	if ha != hb {
+		return ha < hb
+	}
+	return la > lb
+}
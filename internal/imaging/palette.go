@@ -0,0 +1,185 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+
+	"image2spotify/internal/spotify"
+)
+
+// PaletteSize is the number of colors extracted from a cover
+const PaletteSize = 5
+
+// paletteSample is the side of the downscaled copy the palette is computed on
+const paletteSample = 64
+
+// paletteRefinements is the number of k-means passes after median cut
+const paletteRefinements = 5
+
+// Swatch is a palette color with the share of the picture it covers.
+type Swatch struct {
+	Color color.RGBA
+	Share float64
+}
+
+// Hex formats a color as #rrggbb.
+func Hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// CoverPalette returns the palette of a cover, dominant color first, and
+// keeps it in img.Palette so later features don't decode the cover again.
+func CoverPalette(img *spotify.ImageData) []color.RGBA {
+	if img.Palette != nil {
+		return img.Palette
+	}
+	decoded, err := Decode(img.Data)
+	if err != nil {
+		return nil
+	}
+	swatches := Palette(decoded, PaletteSize)
+	img.Palette = make([]color.RGBA, len(swatches))
+	for i, swatch := range swatches {
+		img.Palette[i] = swatch.Color
+	}
+	return img.Palette
+}
+
+// Palette extracts up to n colors from a downscaled copy of the picture.
+// Median cut picks the starting colors and a few k-means passes move them to
+// the centers of their clusters; median cut alone splits by pixel count, so the
+// shares would say nothing about dominance. Swatches are ordered by share,
+// so the first is the dominant color.
+func Palette(img *image.RGBA, n int) []Swatch {
+	sample := Resize(img, paletteSample, paletteSample)
+	pixels := make([][3]uint8, 0, paletteSample*paletteSample)
+	for i := 0; i+3 < len(sample.Pix); i += 4 {
+		if sample.Pix[i+3] < 128 {
+			continue
+		}
+		pixels = append(pixels, [3]uint8{sample.Pix[i], sample.Pix[i+1], sample.Pix[i+2]})
+	}
+	if len(pixels) == 0 {
+		return nil
+	}
+
+	boxes := [][][3]uint8{pixels}
+	for len(boxes) < n {
+		// Split the box with the widest channel range, weighted by its size
+		best, bestChannel, bestScore := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, spread := widestChannel(box)
+			if score := spread * len(box); spread > 0 && score > bestScore {
+				best, bestChannel, bestScore = i, channel, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool { return box[i][bestChannel] < box[j][bestChannel] })
+		median := len(box) / 2
+		boxes[best] = box[:median]
+		boxes = append(boxes, box[median:])
+	}
+
+	centers := make([][3]int, len(boxes))
+	for i, box := range boxes {
+		centers[i] = mean(box)
+	}
+
+	counts := make([]int, len(centers))
+	for pass := 0; pass < paletteRefinements; pass++ {
+		sums := make([][3]int, len(centers))
+		for i := range counts {
+			counts[i] = 0
+		}
+		for _, p := range pixels {
+			nearest := nearestCenter(centers, p)
+			sums[nearest][0] += int(p[0])
+			sums[nearest][1] += int(p[1])
+			sums[nearest][2] += int(p[2])
+			counts[nearest]++
+		}
+		for i := range centers {
+			if counts[i] > 0 {
+				centers[i] = [3]int{sums[i][0] / counts[i], sums[i][1] / counts[i], sums[i][2] / counts[i]}
+			}
+		}
+	}
+
+	swatches := make([]Swatch, 0, len(centers))
+	for i, c := range centers {
+		if counts[i] == 0 {
+			continue
+		}
+		swatches = append(swatches, Swatch{
+			Color: color.RGBA{R: uint8(c[0]), G: uint8(c[1]), B: uint8(c[2]), A: 255},
+			Share: float64(counts[i]) / float64(len(pixels)),
+		})
+	}
+	sort.SliceStable(swatches, func(i, j int) bool { return swatches[i].Share > swatches[j].Share })
+	return swatches
+}
+
+func mean(box [][3]uint8) [3]int {
+	var sum [3]int
+	for _, p := range box {
+		sum[0] += int(p[0])
+		sum[1] += int(p[1])
+		sum[2] += int(p[2])
+	}
+	return [3]int{sum[0] / len(box), sum[1] / len(box), sum[2] / len(box)}
+}
+
+func nearestCenter(centers [][3]int, p [3]uint8) int {
+	nearest, bestDist := 0, -1
+	for i, c := range centers {
+		dr, dg, db := c[0]-int(p[0]), c[1]-int(p[1]), c[2]-int(p[2])
+		if dist := dr*dr + dg*dg + db*db; bestDist < 0 || dist < bestDist {
+			nearest, bestDist = i, dist
+		}
+	}
+	return nearest
+}
+
+// widestChannel returns the RGB channel with the largest range in the box.
+func widestChannel(box [][3]uint8) (channel, spread int) {
+	for c := 0; c < 3; c++ {
+		lo, hi := 255, 0
+		for _, p := range box {
+			lo = min(lo, int(p[c]))
+			hi = max(hi, int(p[c]))
+		}
+		if hi-lo > spread {
+			channel, spread = c, hi-lo
+		}
+	}
+	return channel, spread
+}
+
+// PaletteCard draws the cover with a band of its palette colors below,
+// each color as wide as its share of the cover.
+func PaletteCard(cover *image.RGBA, swatches []Swatch, width int) *image.RGBA {
+	band := width / 4
+	dst := image.NewRGBA(image.Rect(0, 0, width, width+band))
+	draw.Draw(dst, image.Rect(0, 0, width, width), Fill(cover, width, width), image.Point{}, draw.Src)
+
+	x := 0
+	for i, swatch := range swatches {
+		w := int(swatch.Share * float64(width))
+		if i == len(swatches)-1 {
+			w = width - x
+		}
+		draw.Draw(dst, image.Rect(x, width, x+w, width+band), image.NewUniform(swatch.Color), image.Point{}, draw.Src)
+		x += w
+	}
+	return dst
+}
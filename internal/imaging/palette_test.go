@@ -0,0 +1,85 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// stripes draws vertical stripes, each color as wide as its share.
+func stripes(size int, colors []color.RGBA, shares []float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	x := 0
+	for i, c := range colors {
+		w := int(shares[i] * float64(size))
+		if i == len(colors)-1 {
+			w = size - x
+		}
+		draw.Draw(img, image.Rect(x, 0, x+w, size), image.NewUniform(c), image.Point{}, draw.Src)
+		x += w
+	}
+	return img
+}
+
+func colorDistance(a, b color.RGBA) float64 {
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+func TestPaletteDominantColors(t *testing.T) {
+	red := color.RGBA{R: 220, G: 30, B: 40, A: 255}
+	blue := color.RGBA{R: 20, G: 60, B: 200, A: 255}
+	yellow := color.RGBA{R: 240, G: 220, B: 30, A: 255}
+	img := stripes(640, []color.RGBA{blue, red, yellow}, []float64{0.3, 0.5, 0.2})
+
+	swatches := Palette(img, 3)
+	if len(swatches) != 3 {
+		t.Fatalf("got %d swatches, want 3", len(swatches))
+	}
+
+	want := []struct {
+		color color.RGBA
+		share float64
+	}{{red, 0.5}, {blue, 0.3}, {yellow, 0.2}}
+	total := 0.0
+	for i, w := range want {
+		got := swatches[i]
+		if d := colorDistance(got.Color, w.color); d > 30 {
+			t.Errorf("swatch %d = %s, want about %s", i, Hex(got.Color), Hex(w.color))
+		}
+		if math.Abs(got.Share-w.share) > 0.05 {
+			t.Errorf("swatch %d share = %.2f, want about %.2f", i, got.Share, w.share)
+		}
+		total += got.Share
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("shares add up to %f, want 1", total)
+	}
+}
+
+func TestPaletteFewColors(t *testing.T) {
+	solid := color.RGBA{R: 10, G: 120, B: 90, A: 255}
+	img := stripes(100, []color.RGBA{solid}, []float64{1})
+
+	swatches := Palette(img, 5)
+	if len(swatches) != 1 {
+		t.Fatalf("single color cover: got %d swatches, want 1", len(swatches))
+	}
+	if swatches[0].Color != solid || swatches[0].Share != 1 {
+		t.Errorf("swatch = %+v, want %s covering everything", swatches[0], Hex(solid))
+	}
+}
+
+func TestPaletteTransparent(t *testing.T) {
+	if swatches := Palette(image.NewRGBA(image.Rect(0, 0, 32, 32)), 5); swatches != nil {
+		t.Errorf("transparent picture: got %v, want no swatches", swatches)
+	}
+}
+
+func TestHex(t *testing.T) {
+	if got := Hex(color.RGBA{R: 255, G: 8, B: 171, A: 255}); got != "#ff08ab" {
+		t.Errorf("Hex = %q, want #ff08ab", got)
+	}
+}
@@ -0,0 +1,256 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// PosterWidth is the default width of a tracklist poster
+const PosterWidth = 1080
+
+// posterTitleLines is the number of lines the title may wrap to
+const posterTitleLines = 2
+
+// PosterTrack is one row of the tracklist.
+type PosterTrack struct {
+	Number   int
+	Title    string
+	Artists  string // empty hides the artist line
+	Duration time.Duration
+}
+
+// Tracklist is the text of a tracklist poster.
+type Tracklist struct {
+	Title    string
+	Subtitle string // artists or playlist owner
+	Details  string // type, year, track count and length
+	Tracks   []PosterTrack
+	More     int // tracks left out of the poster
+}
+
+// The Go fonts are embedded in the binary, so posters need no system fonts.
+// They cover Latin, Greek and Cyrillic; other characters are left out.
+var (
+	fontsOnce sync.Once
+	fontsErr  error
+	fontBold  *opentype.Font
+	fontText  *opentype.Font
+	fontMono  *opentype.Font
+)
+
+func loadFonts() error {
+	fontsOnce.Do(func() {
+		if fontBold, fontsErr = opentype.Parse(gobold.TTF); fontsErr != nil {
+			return
+		}
+		if fontText, fontsErr = opentype.Parse(goregular.TTF); fontsErr != nil {
+			return
+		}
+		fontMono, fontsErr = opentype.Parse(gomono.TTF)
+	})
+	return fontsErr
+}
+
+// posterFaces are the font faces of one poster, sized for its width.
+type posterFaces struct {
+	title, subtitle, details, track, artist, mono font.Face
+}
+
+func newPosterFaces(scale float64) (*posterFaces, error) {
+	if err := loadFonts(); err != nil {
+		return nil, err
+	}
+	faces := &posterFaces{}
+	for _, f := range []struct {
+		face *font.Face
+		font *opentype.Font
+		size float64
+	}{
+		{&faces.title, fontBold, 60},
+		{&faces.subtitle, fontText, 34},
+		{&faces.details, fontText, 27},
+		{&faces.track, fontText, 30},
+		{&faces.artist, fontText, 24},
+		{&faces.mono, fontMono, 26},
+	} {
+		face, err := opentype.NewFace(f.font, &opentype.FaceOptions{Size: f.size * scale, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, err
+		}
+		*f.face = face
+	}
+	return faces, nil
+}
+
+// TracklistPoster draws the cover at the top and the title, subtitle,
+// details and tracklist below it, on a dark shade of the cover's dominant
+// color. The poster is as tall as its text needs; cover may be nil.
+func TracklistPoster(cover *image.RGBA, list Tracklist, width int) (*image.RGBA, error) {
+	scale := float64(width) / PosterWidth
+	px := func(v float64) int { return int(v*scale + 0.5) }
+
+	faces, err := newPosterFaces(scale)
+	if err != nil {
+		return nil, err
+	}
+
+	margin := px(64)
+	textWidth := width - 2*margin
+	title := wrapText(faces.title, list.Title, textWidth, posterTitleLines)
+
+	rowHeight := px(56)
+	for _, track := range list.Tracks {
+		if track.Artists != "" {
+			rowHeight = px(84)
+			break
+		}
+	}
+	rows := len(list.Tracks)
+	if list.More > 0 {
+		rows++
+	}
+
+	height := margin
+	if cover != nil {
+		height += textWidth + px(52)
+	}
+	height += len(title)*px(72) + px(50) + px(44) + px(40) + rows*rowHeight + margin
+
+	background := color.RGBA{R: 24, G: 24, B: 24, A: 255}
+	if cover != nil {
+		if swatches := Palette(cover, 1); len(swatches) > 0 {
+			c := swatches[0].Color
+			background = color.RGBA{R: c.R/4 + 12, G: c.G/4 + 12, B: c.B/4 + 12, A: 255}
+		}
+	}
+	primary := color.RGBA{R: 250, G: 250, B: 250, A: 255}
+	secondary := color.RGBA{R: 190, G: 190, B: 190, A: 255}
+	dim := color.RGBA{R: 140, G: 140, B: 140, A: 255}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Rect, image.NewUniform(background), image.Point{}, draw.Src)
+
+	y := margin
+	if cover != nil {
+		square := Fill(cover, textWidth, textWidth)
+		draw.Draw(dst, image.Rect(margin, y, margin+textWidth, y+textWidth), square, image.Point{}, draw.Src)
+		y += textWidth + px(52)
+	}
+
+	for _, line := range title {
+		drawText(dst, faces.title, line, margin, y, primary)
+		y += px(72)
+	}
+	drawText(dst, faces.subtitle, fitText(faces.subtitle, list.Subtitle, textWidth), margin, y, secondary)
+	y += px(50)
+	drawText(dst, faces.details, fitText(faces.details, list.Details, textWidth), margin, y, dim)
+	y += px(44)
+	draw.Draw(dst, image.Rect(margin, y, margin+textWidth, y+max(px(2), 1)), image.NewUniform(dim), image.Point{}, draw.Src)
+	y += px(40)
+
+	numberWidth := font.MeasureString(faces.mono, fmt.Sprintf("%d", max(len(list.Tracks)+list.More, 10))).Ceil()
+	durationWidth := font.MeasureString(faces.mono, "00:00").Ceil()
+	gap := px(24)
+	titleX := margin + numberWidth + gap
+	titleWidth := textWidth - numberWidth - durationWidth - 2*gap
+
+	for _, track := range list.Tracks {
+		number := fmt.Sprintf("%d", track.Number)
+		drawText(dst, faces.mono, number, titleX-gap-font.MeasureString(faces.mono, number).Ceil(), y+px(3), dim)
+		drawText(dst, faces.track, fitText(faces.track, track.Title, titleWidth), titleX, y, primary)
+		if track.Artists != "" {
+			drawText(dst, faces.artist, fitText(faces.artist, track.Artists, titleWidth), titleX, y+px(38), dim)
+		}
+		if track.Duration > 0 {
+			duration := FormatDuration(track.Duration)
+			drawText(dst, faces.mono, duration, margin+textWidth-font.MeasureString(faces.mono, duration).Ceil(), y+px(3), dim)
+		}
+		y += rowHeight
+	}
+	if list.More > 0 {
+		drawText(dst, faces.track, fmt.Sprintf("+ %d more", list.More), titleX, y, dim)
+	}
+
+	return dst, nil
+}
+
+// FormatDuration formats a track length as m:ss.
+func FormatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// drawText draws one line of text with its top at y.
+func drawText(dst *image.RGBA, face font.Face, text string, x, y int, c color.Color) {
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y+face.Metrics().Ascent.Ceil()),
+	}
+	d.DrawString(supported(face, text))
+}
+
+// supported drops the characters the font has no glyphs for.
+func supported(face font.Face, text string) string {
+	return strings.Map(func(r rune) rune {
+		if _, ok := face.GlyphAdvance(r); !ok && r != ' ' {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// fitText shortens text with "…" until it fits in width pixels.
+func fitText(face font.Face, text string, width int) string {
+	text = strings.TrimSpace(supported(face, text))
+	if font.MeasureString(face, text).Ceil() <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "…"
+		if font.MeasureString(face, candidate).Ceil() <= width {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// wrapText splits text into at most maxLines lines that fit in width pixels,
+// shortening the last line with "…" when the text doesn't fit.
+func wrapText(face font.Face, text string, width, maxLines int) []string {
+	words := strings.Fields(supported(face, text))
+	var lines []string
+	line := ""
+	for i, word := range words {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line == "" || font.MeasureString(face, candidate).Ceil() <= width {
+			line = candidate
+			continue
+		}
+		if len(lines) == maxLines-1 {
+			line = strings.Join(append([]string{line}, words[i:]...), " ")
+			break
+		}
+		lines = append(lines, fitText(face, line, width))
+		line = word
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, fitText(face, line, width))
+	}
+	return lines
+}
@@ -0,0 +1,132 @@
+// Package imaging renders collages, palettes and other pictures from covers
+// with the standard image packages.
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// Decode decodes a JPEG or PNG cover into RGBA.
+func Decode(data []byte) (*image.RGBA, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return toRGBA(src), nil
+}
+
+// EncodeJPEG encodes a rendered picture for sending.
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, src, b.Min, draw.Src)
+	return dst
+}
+
+// Resize scales src to w×h. Each target pixel averages the source pixels it
+// covers, which keeps downscaled covers smooth; upscaling repeats pixels.
+func Resize(src *image.RGBA, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == 0 || sh == 0 || w == 0 || h == 0 {
+		return dst
+	}
+
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// Fill scales src to cover w×h and crops the overflow around the center.
+func Fill(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == 0 || sh == 0 {
+		return image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	// Crop the source to the target aspect ratio first
+	crop := src.Rect
+	if sw*h > sh*w {
+		cw := sh * w / h
+		crop.Min.X += (sw - cw) / 2
+		crop.Max.X = crop.Min.X + cw
+	} else {
+		ch := sw * h / w
+		crop.Min.Y += (sh - ch) / 2
+		crop.Max.Y = crop.Min.Y + ch
+	}
+	return Resize(toRGBA(src.SubImage(crop)), w, h)
+}
+
+// ResizeSmooth scales src to w×h with bilinear interpolation. Unlike Resize it
+// gives smooth results when enlarging, e.g. for blurred backdrops.
+func ResizeSmooth(src *image.RGBA, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == 0 || sh == 0 || w == 0 || h == 0 {
+		return dst
+	}
+
+	for y := 0; y < h; y++ {
+		fy := max((float64(y)+0.5)*float64(sh)/float64(h)-0.5, 0)
+		y0 := min(int(fy), sh-1)
+		y1 := min(y0+1, sh-1)
+		dy := fy - float64(y0)
+		for x := 0; x < w; x++ {
+			fx := max((float64(x)+0.5)*float64(sw)/float64(w)-0.5, 0)
+			x0 := min(int(fx), sw-1)
+			x1 := min(x0+1, sw-1)
+			dx := fx - float64(x0)
+
+			i00, i10 := src.PixOffset(x0, y0), src.PixOffset(x1, y0)
+			i01, i11 := src.PixOffset(x0, y1), src.PixOffset(x1, y1)
+			j := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				top := float64(src.Pix[i00+c])*(1-dx) + float64(src.Pix[i10+c])*dx
+				bottom := float64(src.Pix[i01+c])*(1-dx) + float64(src.Pix[i11+c])*dx
+				dst.Pix[j+c] = uint8(top*(1-dy) + bottom*dy + 0.5)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,96 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// gifColors is the palette size of a GIF frame
+const gifColors = 256
+
+// Slideshow renders the encoded covers into an endlessly looping GIF of
+// size×size frames, each shown for delay. Covers stay encoded until their
+// frame is made, and at most runtime.NumCPU() are decoded at once, so only
+// the small paletted frames pile up. Covers that fail to decode are skipped.
+// Every frame gets its own palette, so covers keep their colors.
+func Slideshow(covers [][]byte, size int, delay time.Duration) *gif.GIF {
+	frames := make([]*image.Paletted, len(covers))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, runtime.NumCPU())
+	for i, cover := range covers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if decoded, err := Decode(cover); err == nil {
+				frames[i] = gifFrame(decoded, size)
+			}
+		}()
+	}
+	wg.Wait()
+
+	kept := frames[:0]
+	for _, frame := range frames {
+		if frame != nil {
+			kept = append(kept, frame)
+		}
+	}
+	frames = kept
+
+	// GIF delays are counted in hundredths of a second
+	centiseconds := max(int(delay/(10*time.Millisecond)), 2)
+	g := &gif.GIF{
+		Image:     frames,
+		Delay:     make([]int, len(frames)),
+		Disposal:  make([]byte, len(frames)),
+		LoopCount: 0,
+	}
+	for i := range frames {
+		g.Delay[i] = centiseconds
+		g.Disposal[i] = gif.DisposalNone
+	}
+	return g
+}
+
+// EncodeGIF encodes a GIF made by Slideshow.
+func EncodeGIF(g *gif.GIF) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gifFrame crops the cover to a square and reduces it to its own palette
+// with Floyd-Steinberg dithering.
+func gifFrame(cover *image.RGBA, size int) *image.Paletted {
+	var square *image.RGBA
+	if cover.Rect.Dx() >= size && cover.Rect.Dy() >= size {
+		square = Fill(cover, size, size)
+	} else {
+		// Small covers are enlarged smoothly instead of with blocky pixels
+		side := min(cover.Rect.Dx(), cover.Rect.Dy())
+		square = ResizeSmooth(Fill(cover, side, side), size, size)
+	}
+
+	swatches := Palette(square, gifColors)
+	palette := make(color.Palette, 0, len(swatches)+1)
+	for _, swatch := range swatches {
+		palette = append(palette, swatch.Color)
+	}
+	if len(palette) == 0 {
+		palette = append(palette, color.Black)
+	}
+
+	frame := image.NewPaletted(square.Rect, palette)
+	draw.FloydSteinberg.Draw(frame, square.Rect, square, square.Rect.Min)
+	return frame
+}
@@ -0,0 +1,84 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// Telegram static sticker limits: the longer side is exactly 512 pixels and
+// the PNG file is at most 512 KB.
+const (
+	StickerSize     = 512
+	MaxStickerBytes = 512 * 1024
+)
+
+// stickerReductions are tried in order when a full-color PNG is too large:
+// fewer colors and no dithering give smaller files at some cost in quality.
+var stickerReductions = []struct {
+	colors int
+	dither bool
+}{
+	{256, true},
+	{128, true},
+	{64, true},
+	{64, false},
+}
+
+// Sticker scales the cover so its longer side is 512 pixels and encodes it as
+// PNG. Photos rarely fit in 512 KB as full-color PNG, so larger results are
+// reduced to a 256-color palette with Floyd-Steinberg dithering, and further
+// while the file is still over the limit.
+func Sticker(cover *image.RGBA) ([]byte, error) {
+	w, h := cover.Rect.Dx(), cover.Rect.Dy()
+	if w >= h {
+		w, h = StickerSize, max(h*StickerSize/max(w, 1), 1)
+	} else {
+		w, h = max(w*StickerSize/h, 1), StickerSize
+	}
+
+	var scaled *image.RGBA
+	if cover.Rect.Dx() >= w {
+		scaled = Resize(cover, w, h)
+	} else {
+		scaled = ResizeSmooth(cover, w, h)
+	}
+
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	var buf bytes.Buffer
+	if err := encoder.Encode(&buf, scaled); err != nil {
+		return nil, err
+	}
+	if buf.Len() <= MaxStickerBytes {
+		return buf.Bytes(), nil
+	}
+
+	for _, reduction := range stickerReductions {
+		swatches := Palette(scaled, reduction.colors)
+		palette := make(color.Palette, 0, len(swatches))
+		for _, swatch := range swatches {
+			palette = append(palette, swatch.Color)
+		}
+		if len(palette) == 0 {
+			break
+		}
+		paletted := image.NewPaletted(scaled.Rect, palette)
+		if reduction.dither {
+			draw.FloydSteinberg.Draw(paletted, scaled.Rect, scaled, scaled.Rect.Min)
+		} else {
+			draw.Draw(paletted, scaled.Rect, scaled, scaled.Rect.Min, draw.Src)
+		}
+
+		buf.Reset()
+		if err := encoder.Encode(&buf, paletted); err != nil {
+			return nil, err
+		}
+		if buf.Len() <= MaxStickerBytes {
+			return buf.Bytes(), nil
+		}
+	}
+	return nil, fmt.Errorf("sticker is %d bytes, over the %d byte limit", buf.Len(), MaxStickerBytes)
+}
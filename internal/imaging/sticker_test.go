@@ -0,0 +1,49 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func TestStickerFitsLimits(t *testing.T) {
+	// Noise doesn't compress, so the full-color PNG is over the limit
+	noise := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	rng := rand.New(rand.NewSource(1))
+	rng.Read(noise.Pix)
+	for i := 3; i < len(noise.Pix); i += 4 {
+		noise.Pix[i] = 255
+	}
+
+	data, err := Sticker(noise)
+	if err != nil {
+		t.Fatalf("Sticker error: %v", err)
+	}
+	if len(data) > MaxStickerBytes {
+		t.Errorf("sticker is %d bytes, over %d", len(data), MaxStickerBytes)
+	}
+
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("sticker is not a PNG: %v", err)
+	}
+	if size := decoded.Bounds().Size(); size != image.Pt(StickerSize, 384) {
+		t.Errorf("sticker size = %v, want 512x384", size)
+	}
+}
+
+func TestStickerSmallCover(t *testing.T) {
+	data, err := Sticker(image.NewRGBA(image.Rect(0, 0, 64, 128)))
+	if err != nil {
+		t.Fatalf("Sticker error: %v", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("sticker is not a PNG: %v", err)
+	}
+	if size := decoded.Bounds().Size(); size != image.Pt(256, StickerSize) {
+		t.Errorf("sticker size = %v, want 256x512", size)
+	}
+}
@@ -0,0 +1,84 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Wallpaper presets
+var (
+	PhoneSize   = image.Pt(1080, 1920)
+	DesktopSize = image.Pt(2560, 1440)
+)
+
+// Wallpaper backdrop styles
+const (
+	BackdropBlur    = "blur"
+	BackdropPalette = "palette"
+)
+
+// Share of the shorter wallpaper side taken by the cover
+const wallpaperCoverShare = 0.75
+
+// Wallpaper centers the cover on a size.X×size.Y backdrop: the cover itself
+// blurred and darkened, or a gradient of its two main colors.
+func Wallpaper(cover *image.RGBA, size image.Point, backdrop string) *image.RGBA {
+	var dst *image.RGBA
+	if backdrop == BackdropPalette {
+		dst = gradientBackdrop(cover, size)
+	} else {
+		dst = blurredBackdrop(cover, size)
+	}
+
+	side := int(float64(min(size.X, size.Y)) * wallpaperCoverShare)
+	at := image.Pt((size.X-side)/2, (size.Y-side)/2)
+	draw.Draw(dst, image.Rectangle{Min: at, Max: at.Add(image.Pt(side, side))},
+		ResizeSmooth(cover, side, side), image.Point{}, draw.Src)
+	return dst
+}
+
+// blurredBackdrop fills the wallpaper with the cover, blurred by shrinking it
+// to a few pixels and enlarging it smoothly again.
+func blurredBackdrop(cover *image.RGBA, size image.Point) *image.RGBA {
+	small := Fill(cover, max(size.X/60, 1), max(size.Y/60, 1))
+	// Enlarging in two steps hides the bilinear seams
+	dst := ResizeSmooth(ResizeSmooth(small, max(size.X/8, 1), max(size.Y/8, 1)), size.X, size.Y)
+	darken(dst, 0.6)
+	return dst
+}
+
+// gradientBackdrop fills the wallpaper with a vertical gradient from the
+// dominant color of the cover to its second color.
+func gradientBackdrop(cover *image.RGBA, size image.Point) *image.RGBA {
+	swatches := Palette(cover, PaletteSize)
+	top, bottom := color.RGBA{A: 255}, color.RGBA{A: 255}
+	if len(swatches) > 0 {
+		top, bottom = swatches[0].Color, swatches[0].Color
+	}
+	if len(swatches) > 1 {
+		bottom = swatches[1].Color
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	for y := 0; y < size.Y; y++ {
+		t := float64(y) / float64(max(size.Y-1, 1))
+		row := color.RGBA{
+			R: uint8(float64(top.R)*(1-t) + float64(bottom.R)*t),
+			G: uint8(float64(top.G)*(1-t) + float64(bottom.G)*t),
+			B: uint8(float64(top.B)*(1-t) + float64(bottom.B)*t),
+			A: 255,
+		}
+		draw.Draw(dst, image.Rect(0, y, size.X, y+1), image.NewUniform(row), image.Point{}, draw.Src)
+	}
+	return dst
+}
+
+// darken scales the color channels by factor.
+func darken(img *image.RGBA, factor float64) {
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		img.Pix[i] = uint8(float64(img.Pix[i]) * factor)
+		img.Pix[i+1] = uint8(float64(img.Pix[i+1]) * factor)
+		img.Pix[i+2] = uint8(float64(img.Pix[i+2]) * factor)
+	}
+}
@@ -2,6 +2,7 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync/atomic"
@@ -21,7 +22,6 @@ type Processor struct {
 	timeout            time.Duration
 }
 
-
 func NewProcessor(
 	spotifyClient *spotify.Client,
 	playlistManager *spotify.PlaylistManager,
@@ -40,92 +40,107 @@ func NewProcessor(
 	}
 }
 
-
 func (p *Processor) GetSpotifyClient() *spotify.Client {
 	return p.spotifyClient
 }
 
-// StreamProcessURL обрабатывает URL и вызывает callback для каждого скачанного изображения
+// Summary описывает результат обработки для итогового сообщения
+type Summary struct {
+	Links       int                     // ссылки, прочитанные успешно
+	FailedLinks []FailedLink            // ссылки, которые не удалось прочитать
+	Partial     []*spotify.PartialError // ссылки, прочитанные не полностью
+	Images      int                     // уникальные обложки
+	Downloaded  int                     // успешно скачанные обложки
+	Cached      int                     // обложки, уже загруженные в Telegram
+	TrackURIs   []string                // треки для автоплейлиста
+	NoArtwork   int                     // треки без обложки
+	Unavailable int                     // треки, недоступные в выбранном рынке
+}
+
+// Options задаёт параметры одной обработки
+type Options struct {
+	spotify.FetchOptions
+	Resolution spotify.Resolution // размер скачиваемых обложек
+	FileIDs    FileIDLookup       // если задан, известные обложки не скачиваются
+
+	// Accept, если задан, вызывается перед постановкой новой обложки в очередь.
+	// Обложки, для которых он вернул false, не скачиваются.
+	Accept func() bool
+}
+
+// FileIDLookup находит file_id обложки, которая уже загружалась в Telegram
+type FileIDLookup interface {
+	FileID(imageURL string) string
+}
+
+type FailedLink struct {
+	Link spotify.Link
+	Err  error
+}
+
+// StreamProcessURL обрабатывает ссылки одной задачей и вызывает callback для каждого скачанного изображения.
+// Треки читаются постранично, и скачивание начинается, не дожидаясь конца плейлиста,
+// поэтому total в callback'ах растёт по мере чтения. Обложки дедуплицируются по всем ссылкам.
 func (p *Processor) StreamProcessURL(
 	ctx context.Context,
-	url string,
+	links []spotify.Link,
+	opts Options,
 	imageCallback func(img *spotify.ImageData, index, total int) error,
 	progressCallback func(current, total int),
-) error {
+) (*Summary, error) {
 	processCtx, cancel := context.WithTimeout(ctx, p.timeout)
 	defer cancel()
 
-	tracks, sourceID, urlType, err := p.spotifyClient.GetTracks(processCtx, url)
-	if err != nil {
-		return fmt.Errorf("failed to get tracks: %w", err)
-	}
-
-	if len(tracks) == 0 {
-		return fmt.Errorf("no tracks found")
-	}
-
-	log.Info().
-		Str("url", url).
-		Str("type", urlType).
-		Str("source_id", sourceID).
-		Int("track_count", len(tracks)).
-		Msg("Processing URL")
-
-	uniqueImages := make(map[string]string)
-	for _, track := range tracks {
-		if len(track.Album.Images) == 0 {
-			continue
-		}
-		imageURL := track.Album.Images[0].URL
-		trackID := track.ID
-		if trackID == "" {
-			trackID = fmt.Sprintf("unknown_%d", len(uniqueImages))
-		}
-		if _, exists := uniqueImages[imageURL]; !exists {
-			uniqueImages[imageURL] = trackID
-		}
-	}
+	taskResults := make(chan *spotify.ImageData)
+	resultsChan := relayResults(processCtx, taskResults)
 
-	total := len(uniqueImages)
-	if total == 0 {
-		return fmt.Errorf("no images found")
+	type feedResult struct {
+		summary *Summary
+		err     error
 	}
-
-	log.Debug().Int("unique_images", total).Msg("Found unique images")
-
-	resultsChan := make(chan *spotify.ImageData, total)
+	var queued int32
+	feedDone := make(chan feedResult, 1)
+	go func() {
+		summary, err := p.feedTasks(processCtx, links, opts, taskResults, &queued)
+		feedDone <- feedResult{summary: summary, err: err}
+	}()
+
+	summary := &Summary{}
 	var downloadedCount int32
 	var successCount int32
-
-	// Submit tasks
-	submitted := 0
-	for imageURL, trackID := range uniqueImages {
-		task := &DownloadTask{
-			URL:     imageURL,
-			TrackID: trackID,
-			Result:  resultsChan,
-		}
-		if p.workerPool.Submit(task) {
-			submitted++
-		}
-	}
-
-	log.Debug().Int("submitted", submitted).Int("total", total).Msg("Submitted download tasks")
+	var cachedCount int32
 
 	// Обрабатываем результаты по мере поступления
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
 	lastReported := int32(0)
+	feeding := true
 
-	for int(atomic.LoadInt32(&downloadedCount)) < total {
+	for feeding || atomic.LoadInt32(&downloadedCount) < atomic.LoadInt32(&queued) {
 		select {
+		case fed := <-feedDone:
+			feeding = false
+			summary = fed.summary
+			if fed.err != nil {
+				return summary, fed.err
+			}
+			summary.Images = int(atomic.LoadInt32(&queued))
+			if summary.Images == 0 {
+				return summary, fmt.Errorf("no images found")
+			}
+			log.Debug().Int("unique_images", summary.Images).Msg("All tracks read")
+
 		case result := <-resultsChan:
 			current := atomic.AddInt32(&downloadedCount, 1)
+			total := int(atomic.LoadInt32(&queued))
 
-			if len(result.Data) > 0 {
+			if result.FileID != "" {
+				atomic.AddInt32(&cachedCount, 1)
+			}
+			if len(result.Data) > 0 || result.FileID != "" {
 				success := atomic.AddInt32(&successCount, 1)
-				
+
 				// КЛЮЧЕВОЙ МОМЕНТ: Вызываем callback сразу для каждого изображения
 				if imageCallback != nil {
 					if err := imageCallback(result, int(success), total); err != nil {
@@ -134,7 +149,7 @@ func (p *Processor) StreamProcessURL(
 				}
 			}
 
-			if progressCallback != nil && (current%10 == 0 || current == int32(total)) {
+			if progressCallback != nil && (current%10 == 0 || int(current) == total) {
 				progressCallback(int(current), total)
 			}
 
@@ -145,7 +160,7 @@ func (p *Processor) StreamProcessURL(
 				log.Debug().
 					Int32("downloaded", current).
 					Int32("successful", success).
-					Int("total", total).
+					Int32("total", atomic.LoadInt32(&queued)).
 					Msg("Download progress")
 				lastReported = current
 			}
@@ -153,25 +168,181 @@ func (p *Processor) StreamProcessURL(
 		case <-processCtx.Done():
 			log.Error().
 				Int32("downloaded", atomic.LoadInt32(&downloadedCount)).
-				Int("total", total).
+				Int32("total", atomic.LoadInt32(&queued)).
 				Msg("Processing timeout")
-			return fmt.Errorf("processing timeout")
+			return summary, fmt.Errorf("processing timeout")
 		}
 	}
 
-	close(resultsChan)
-
 	finalSuccess := int(atomic.LoadInt32(&successCount))
+	summary.Downloaded = finalSuccess
+	summary.Cached = int(atomic.LoadInt32(&cachedCount))
 	if finalSuccess == 0 {
-		return fmt.Errorf("no images were downloaded successfully")
+		return summary, fmt.Errorf("no images were downloaded successfully")
 	}
 
 	log.Info().
 		Int("successful", finalSuccess).
-		Int("total", total).
+		Int("cached", summary.Cached).
+		Int("total", summary.Images).
 		Msg("Download completed")
 
-	return nil
+	return summary, nil
+}
+
+// feedTasks читает треки по страницам и сразу отправляет новые обложки в пул.
+// queued растёт на каждую отправленную задачу.
+func (p *Processor) feedTasks(
+	ctx context.Context,
+	links []spotify.Link,
+	opts Options,
+	results chan *spotify.ImageData,
+	queued *int32,
+) (*Summary, error) {
+	summary := &Summary{}
+	uniqueImages := make(map[string]bool)
+	seenTracks := make(map[string]bool)
+	var lastErr error
+
+	for _, link := range links {
+		trackCount := 0
+		var linkErr error
+
+		for batch := range p.spotifyClient.StreamTracks(ctx, link, opts.FetchOptions) {
+			if batch.Err != nil {
+				linkErr = batch.Err
+				continue
+			}
+			trackCount += len(batch.Tracks)
+
+			for _, track := range batch.Tracks {
+				originalID := track.OriginalID()
+				if originalID != "" && seenTracks[originalID] {
+					continue
+				}
+				seenTracks[originalID] = true
+
+				if track.Unavailable() {
+					summary.Unavailable++
+				} else if track.ID != "" && track.Type != "episode" {
+					summary.TrackURIs = append(summary.TrackURIs, fmt.Sprintf("spotify:track:%s", track.ID))
+				}
+
+				if len(track.Album.Images) == 0 {
+					summary.NoArtwork++
+					continue
+				}
+				imageURL, fallbackURL := spotify.PickImage(track.Album.Images, opts.Resolution)
+				if uniqueImages[imageURL] {
+					continue
+				}
+				uniqueImages[imageURL] = true
+				if opts.Accept != nil && !opts.Accept() {
+					continue
+				}
+
+				trackID := track.ID
+				if trackID == "" {
+					trackID = track.Album.ID
+				}
+				if trackID == "" {
+					trackID = fmt.Sprintf("unknown_%d", len(uniqueImages))
+				}
+
+				task := &DownloadTask{
+					Ctx:         ctx,
+					URL:         imageURL,
+					FallbackURL: fallbackURL,
+					TrackID:     trackID,
+					Position:    len(uniqueImages),
+					Album:       track.Album,
+					Artists:     track.CoverArtists(),
+					Result:      results,
+				}
+				if opts.FileIDs != nil {
+					task.FileID = opts.FileIDs.FileID(imageURL)
+				}
+				atomic.AddInt32(queued, 1)
+				if !p.workerPool.Submit(task) {
+					atomic.AddInt32(queued, -1)
+				}
+			}
+		}
+
+		if partialErr, ok := spotify.AsPartialError(linkErr); ok {
+			log.Warn().
+				Err(partialErr.Err).
+				Str("url", link.Raw).
+				Int("total", partialErr.Total).
+				Int("fetched", partialErr.Fetched).
+				Msg("Tracks read partially")
+			summary.Partial = append(summary.Partial, partialErr)
+			linkErr = nil
+		}
+		if ctx.Err() != nil {
+			return summary, ctx.Err()
+		}
+		if linkErr != nil {
+			log.Warn().Err(linkErr).Str("url", link.Raw).Msg("Failed to get tracks")
+			summary.FailedLinks = append(summary.FailedLinks, FailedLink{Link: link, Err: linkErr})
+			lastErr = linkErr
+			continue
+		}
+		summary.Links++
+
+		log.Info().
+			Str("url", link.Raw).
+			Str("type", string(link.Kind)).
+			Str("source_id", link.ID).
+			Int("track_count", trackCount).
+			Int32("queued_images", atomic.LoadInt32(queued)).
+			Msg("Processed URL")
+	}
+
+	if summary.Links == 0 {
+		return summary, fmt.Errorf("failed to get tracks: %w", lastErr)
+	}
+
+	return summary, nil
+}
+
+// relayResults буферизует результаты без ограничения, чтобы воркеры пула
+// не ждали, пока callback отправляет предыдущие изображения в Telegram
+func relayResults(ctx context.Context, in <-chan *spotify.ImageData) <-chan *spotify.ImageData {
+	out := make(chan *spotify.ImageData)
+
+	go func() {
+		var queue []*spotify.ImageData
+		for {
+			var send chan<- *spotify.ImageData
+			var next *spotify.ImageData
+			if len(queue) > 0 {
+				send = out
+				next = queue[0]
+			}
+
+			select {
+			case img := <-in:
+				queue = append(queue, img)
+			case send <- next:
+				queue = queue[1:]
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// DownloadImage скачивает одно изображение вне пула, например обложку для обоев.
+// Если imageURL не найден, скачивается fallbackURL.
+func (p *Processor) DownloadImage(ctx context.Context, imageURL, fallbackURL string) ([]byte, error) {
+	data, err := p.workerPool.downloader.Download(ctx, imageURL)
+	if errors.Is(err, spotify.ErrImageNotFound) && fallbackURL != "" {
+		return p.workerPool.downloader.Download(ctx, fallbackURL)
+	}
+	return data, err
 }
 
 func (p *Processor) Shutdown() {
@@ -225,4 +396,4 @@ func (p *Processor) AddToAutoPlaylist(ctx context.Context, trackURIs []string) e
 		Msg("Adding new tracks to auto-playlist")
 
 	return p.playlistManager.AddTracksToPlaylist(ctx, p.autoPlaylistID, newTrackURIs)
-}
\ No newline at end of file
+}
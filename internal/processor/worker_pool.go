@@ -2,6 +2,7 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,9 +13,15 @@ import (
 )
 
 type DownloadTask struct {
-	URL     string
-	TrackID string
-	Result  chan *spotify.ImageData
+	Ctx         context.Context // задача отменяется вместе с обработкой, к которой относится
+	URL         string
+	FallbackURL string // скачивается, если URL не найден (оригинал обложки есть не у всех)
+	TrackID     string
+	Position    int                 // порядок обложки в плейлисте
+	Album       spotify.SimpleAlbum // передаются в ImageData вместе с обложкой
+	Artists     []spotify.Artist
+	FileID      string // обложка уже есть в Telegram, скачивать не нужно
+	Result      chan *spotify.ImageData
 }
 
 type WorkerPool struct {
@@ -39,13 +46,13 @@ func NewWorkerPool(workers int, imageTimeout time.Duration) *WorkerPool {
 	}
 
 	pool.start()
-	
+
 	log.Info().
 		Int("workers", workers).
 		Dur("image_timeout", imageTimeout).
 		Int("queue_size", workers*10).
 		Msg("Worker pool initialized")
-	
+
 	return pool
 }
 
@@ -79,28 +86,46 @@ func (p *WorkerPool) worker(id int) {
 }
 
 func (p *WorkerPool) processTask(task *DownloadTask) {
+	if task.FileID != "" {
+		log.Debug().Str("track_id", task.TrackID).Str("file_id", task.FileID).Msg("Cover already in Telegram, skipping download")
+		p.deliver(task, &spotify.ImageData{
+			FileID:       task.FileID,
+			URL:          task.URL,
+			RequestedURL: task.URL,
+			FallbackURL:  task.FallbackURL,
+			TrackID:      task.TrackID,
+			Position:     task.Position,
+			Album:        task.Album,
+			Artists:      task.Artists,
+		})
+		return
+	}
+
 	maxRetries := 3
+	imageURL := task.URL
 	var data []byte
 	var err error
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
+		if attempt > 0 && err != nil {
 			delay := time.Duration(attempt) * 2 * time.Second
-			
+
 			log.Debug().
 				Str("track_id", task.TrackID).
 				Int("attempt", attempt).
 				Dur("delay", delay).
 				Msg("Retrying download")
-			
+
 			select {
 			case <-time.After(delay):
 			case <-p.ctx.Done():
 				return
+			case <-task.Ctx.Done():
+				return
 			}
 		}
 
-		data, err = p.downloader.Download(p.ctx, task.URL)
+		data, err = p.downloader.Download(task.Ctx, imageURL)
 		if err == nil && len(data) > 0 {
 			log.Debug().
 				Str("track_id", task.TrackID).
@@ -117,30 +142,51 @@ func (p *WorkerPool) processTask(task *DownloadTask) {
 				Int("attempt", attempt+1).
 				Msg("Download failed")
 		}
+
+		// Отсутствующую обложку нет смысла запрашивать повторно
+		if errors.Is(err, spotify.ErrImageNotFound) {
+			if task.FallbackURL == "" || imageURL == task.FallbackURL {
+				break
+			}
+			log.Debug().
+				Str("track_id", task.TrackID).
+				Str("url", imageURL).
+				Msg("Image not found, using fallback")
+			imageURL = task.FallbackURL
+			err = nil
+		}
 	}
 
 	result := &spotify.ImageData{
-		URL:     task.URL,
-		TrackID: task.TrackID,
-		Data:    data,
+		URL:          imageURL,
+		RequestedURL: task.URL,
+		FallbackURL:  task.FallbackURL,
+		TrackID:      task.TrackID,
+		Position:     task.Position,
+		Data:         data,
+		Album:        task.Album,
+		Artists:      task.Artists,
 	}
 
 	if len(data) == 0 {
 		log.Warn().
 			Str("track_id", task.TrackID).
-			Str("url", task.URL).
+			Str("url", imageURL).
 			Int("max_attempts", maxRetries+1).
 			Msg("Failed to download after all retries")
 	}
 
+	p.deliver(task, result)
+}
+
+// deliver передаёт результат задачи, если обработка ещё не отменена
+func (p *WorkerPool) deliver(task *DownloadTask, result *spotify.ImageData) {
 	select {
 	case task.Result <- result:
 	case <-p.ctx.Done():
 		log.Debug().Str("track_id", task.TrackID).Msg("Context cancelled, discarding result")
-	case <-time.After(5 * time.Second):
-		log.Warn().
-			Str("track_id", task.TrackID).
-			Msg("Timeout sending result")
+	case <-task.Ctx.Done():
+		log.Debug().Str("track_id", task.TrackID).Msg("Task cancelled, discarding result")
 	}
 }
 
@@ -151,6 +197,9 @@ func (p *WorkerPool) Submit(task *DownloadTask) bool {
 	case <-p.ctx.Done():
 		log.Debug().Str("track_id", task.TrackID).Msg("Task rejected: context cancelled")
 		return false
+	case <-task.Ctx.Done():
+		log.Debug().Str("track_id", task.TrackID).Msg("Task rejected: task cancelled")
+		return false
 	}
 }
 
@@ -6,11 +6,34 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// playlistItemFields selects the parts of playlist items that Track and
+// PlaylistItem decode, so big playlists don't download available markets and
+// the like. Keep it in sync with those types.
+const playlistItemFields = "items(track(" +
+	"id,name,type,uri,disc_number,track_number,duration_ms,explicit,is_playable,linked_from(id),external_ids,external_urls," +
+	"artists(id,name,uri,external_urls)," +
+	"album(id,name,uri,album_type,total_tracks,release_date,release_date_precision,images,artists(id,name,uri,external_urls),external_urls)," +
+	"images,release_date,show(id,name,uri,publisher,images,external_urls)))"
+
+var (
+	playlistFieldsParam     = url.QueryEscape("name,description,uri,owner(id,display_name),external_urls,images,tracks(total," + playlistItemFields + ")")
+	playlistPageFieldsParam = url.QueryEscape("total," + playlistItemFields)
+	playlistInfoFieldsParam = url.QueryEscape("name,description,uri,owner(id,display_name),external_urls,images,tracks(total)")
 )
 
+// showMarket is sent with show and episode requests when no market is
+// configured: with client credentials Spotify treats podcasts as unavailable
+// unless a market is given.
+const showMarket = "US"
+
 type Client struct {
 	clientID     string
 	clientSecret string
@@ -18,14 +41,74 @@ type Client struct {
 	tokenExpiry  time.Time
 	mu           sync.RWMutex
 	httpClient   *http.Client
+	resolver     Resolver
+
+	// pageConcurrency bounds parallel page requests of one listing
+	pageConcurrency int
+
+	// market is the default ISO country code for track relinking, "" for none
+	market string
+
+	// rateLimitedUntil pauses all requests after a 429
+	rateLimitMu      sync.Mutex
+	rateLimitedUntil time.Time
 }
 
-func NewClient(clientID, clientSecret string) *Client {
+func NewClient(clientID, clientSecret string, pageConcurrency int, market string) *Client {
+	if pageConcurrency < 1 {
+		pageConcurrency = 1
+	}
+
 	return &Client{
-		clientID:     clientID,
-		clientSecret: clientSecret,
-		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		clientID:        clientID,
+		clientSecret:    clientSecret,
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		resolver:        NewHTTPResolver(10 * time.Second),
+		pageConcurrency: pageConcurrency,
+		market:          strings.ToUpper(market),
+	}
+}
+
+// DefaultMarket returns the market used when a request doesn't name one.
+func (c *Client) DefaultMarket() string {
+	return c.market
+}
+
+func (c *Client) marketFor(market string) string {
+	if market != "" {
+		return market
+	}
+	return c.market
+}
+
+// withMarket adds the market parameter to an API URL. Spotify then relinks
+// tracks that are not playable there and reports is_playable.
+func withMarket(apiURL, market string) string {
+	if market == "" {
+		return apiURL
+	}
+	separator := "?"
+	if strings.Contains(apiURL, "?") {
+		separator = "&"
 	}
+	return apiURL + separator + "market=" + market
+}
+
+func podcastMarket(market string) string {
+	if market == "" {
+		return showMarket
+	}
+	return market
+}
+
+// SetResolver replaces the short link resolver.
+func (c *Client) SetResolver(resolver Resolver) {
+	c.resolver = resolver
+}
+
+// ParseLink parses raw with the client's short link resolver.
+func (c *Client) ParseLink(ctx context.Context, raw string) (Link, error) {
+	return ParseLink(ctx, raw, c.resolver)
 }
 
 func (c *Client) getAccessToken(ctx context.Context) (string, error) {
@@ -62,7 +145,7 @@ func (c *Client) getAccessToken(ctx context.Context) (string, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+		return "", newAPIError(resp, body)
 	}
 
 	var result struct {
@@ -80,171 +163,438 @@ func (c *Client) getAccessToken(ctx context.Context) (string, error) {
 	return c.accessToken, nil
 }
 
-func (c *Client) apiRequest(ctx context.Context, url string) ([]byte, error) {
+const (
+	maxAPIAttempts = 4
+	maxRetryAfter  = time.Minute
+)
+
+// waitRateLimit blocks while the client is paused after a 429.
+func (c *Client) waitRateLimit(ctx context.Context) error {
+	c.rateLimitMu.Lock()
+	wait := time.Until(c.rateLimitedUntil)
+	c.rateLimitMu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pauseRequests holds back every request of the client for d, so that
+// concurrent page fetches back off together.
+func (c *Client) pauseRequests(d time.Duration) {
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+
+	if until := time.Now().Add(d); until.After(c.rateLimitedUntil) {
+		c.rateLimitedUntil = until
+	}
+}
+
+// apiRequest performs a GET request and decodes the JSON reply into v straight
+// from the response body. Rate limits (429) and server errors (5xx) are retried
+// with exponential backoff, honouring Retry-After when present. A 429 pauses
+// all requests of the client until Retry-After has passed.
+func (c *Client) apiRequest(ctx context.Context, url string, v interface{}) error {
+	var lastErr error
+
+	for attempt := 0; attempt < maxAPIAttempts; attempt++ {
+		if err := c.waitRateLimit(ctx); err != nil {
+			return err
+		}
+
+		err := c.doAPIRequest(ctx, url, v)
+		if err == nil {
+			return nil
+		}
+
+		apiErr, ok := AsAPIError(err)
+		if !ok || !apiErr.Temporary() {
+			return err
+		}
+		lastErr = err
+
+		delay := time.Duration(1<<attempt) * time.Second
+		if apiErr.RetryAfter > 0 {
+			delay = apiErr.RetryAfter
+		}
+		if delay > maxRetryAfter || attempt == maxAPIAttempts-1 {
+			break
+		}
+
+		log.Debug().
+			Int("status", apiErr.Status).
+			Int("attempt", attempt+1).
+			Dur("delay", delay).
+			Msg("Retrying Spotify API request")
+
+		if apiErr.RateLimited() {
+			c.pauseRequests(delay)
+			continue
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return lastErr
+}
+
+func (c *Client) doAPIRequest(ctx context.Context, url string, v interface{}) error {
 	token, err := c.getAccessToken(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return newAPIError(resp, body)
 	}
 
-	return body, nil
+	return json.NewDecoder(resp.Body).Decode(v)
 }
 
-func (c *Client) GetTrack(ctx context.Context, trackID string) (*Track, error) {
-	data, err := c.apiRequest(ctx, fmt.Sprintf("https://api.spotify.com/v1/tracks/%s", trackID))
-	if err != nil {
-		return nil, err
-	}
+// GetTrack fetches a track. An empty market means the client's default market.
+func (c *Client) GetTrack(ctx context.Context, trackID, market string) (*Track, error) {
+	apiURL := withMarket(fmt.Sprintf("https://api.spotify.com/v1/tracks/%s", trackID), c.marketFor(market))
 
 	var track Track
-	if err := json.Unmarshal(data, &track); err != nil {
+	if err := c.apiRequest(ctx, apiURL, &track); err != nil {
 		return nil, err
 	}
 
 	return &track, nil
 }
 
-func (c *Client) GetAlbumTracks(ctx context.Context, albumID string) ([]Track, error) {
-	data, err := c.apiRequest(ctx, fmt.Sprintf("https://api.spotify.com/v1/albums/%s", albumID))
-	if err != nil {
-		return nil, err
-	}
+// GetAlbumTracks returns all tracks of an album. See GetTracks for partial results.
+func (c *Client) GetAlbumTracks(ctx context.Context, albumID, market string) ([]Track, error) {
+	return collectTracks(func(emit func(TrackBatch)) error {
+		return c.streamAlbumTracks(ctx, albumID, c.marketFor(market), emit)
+	})
+}
 
+func (c *Client) streamAlbumTracks(ctx context.Context, albumID, market string, emit func(TrackBatch)) error {
 	var album Album
-	if err := json.Unmarshal(data, &album); err != nil {
-		return nil, err
-	}
-
-	tracks := album.Tracks.Items
-	for i := range tracks {
-		tracks[i].Album.Images = album.Images
-		tracks[i].Album.Name = album.Name
+	if err := c.fetchPage(ctx, withMarket(fmt.Sprintf("https://api.spotify.com/v1/albums/%s", albumID), market), &album); err != nil {
+		return err
 	}
 
-	offset := 50
-	for offset < album.Tracks.Total {
-		url := fmt.Sprintf("https://api.spotify.com/v1/albums/%s/tracks?offset=%d&limit=50", albumID, offset)
-		pageData, err := c.apiRequest(ctx, url)
-		if err != nil {
-			break
-		}
-
-		var page struct {
-			Items []Track `json:"items"`
-		}
-		if err := json.Unmarshal(pageData, &page); err != nil {
-			break
+	total := album.Tracks.Total
+	fetched := 0
+	emitPage := func(tracks []Track) {
+		for i := range tracks {
+			tracks[i].Album = album.SimpleAlbum
+			tracks[i].Album.ID = albumID
 		}
+		fetched += len(tracks)
+		emit(TrackBatch{Tracks: tracks, Total: total})
+	}
+	emitPage(album.Tracks.Items)
 
-		for i := range page.Items {
-			page.Items[i].Album.Images = album.Images
-			page.Items[i].Album.Name = album.Name
-		}
+	urls := pageURLs(withMarket(fmt.Sprintf("https://api.spotify.com/v1/albums/%s/tracks", albumID), market),
+		len(album.Tracks.Items), total, 50)
+	err := fetchPages(ctx, c, urls, func(page *struct {
+		Items []Track `json:"items"`
+	}) {
+		emitPage(page.Items)
+	})
 
-		tracks = append(tracks, page.Items...)
-		offset += 50
+	if err != nil {
+		return &PartialError{Kind: KindAlbum, ID: albumID, Total: total, Fetched: fetched, Err: err}
 	}
+	return nil
+}
 
-	return tracks, nil
+// GetPlaylistTracks returns all tracks and episodes of a playlist, including
+// those without artwork. See GetTracks for partial results.
+func (c *Client) GetPlaylistTracks(ctx context.Context, playlistID, market string) ([]Track, error) {
+	return collectTracks(func(emit func(TrackBatch)) error {
+		return c.streamPlaylistTracks(ctx, playlistID, c.marketFor(market), emit)
+	})
 }
 
-func (c *Client) GetPlaylistTracks(ctx context.Context, playlistID string) ([]Track, error) {
-	data, err := c.apiRequest(ctx, fmt.Sprintf("https://api.spotify.com/v1/playlists/%s", playlistID))
+func (c *Client) streamPlaylistTracks(ctx context.Context, playlistID, market string, emit func(TrackBatch)) error {
+	var playlist Playlist
+	err := c.fetchPage(ctx, withMarket(fmt.Sprintf("https://api.spotify.com/v1/playlists/%s?additional_types=track,episode&fields=%s",
+		playlistID, playlistFieldsParam), market), &playlist)
 	if err != nil {
-		if strings.Contains(err.Error(), "404") && strings.HasPrefix(playlistID, "37i9dQZF") {
-			return nil, fmt.Errorf("editorial playlists are not accessible via API")
+		return playlistError(playlistID, err)
+	}
+
+	total := playlist.Tracks.Total
+	fetched := 0
+	emitPage := func(items []PlaylistEntry) {
+		tracks := make([]Track, 0, len(items))
+		for _, item := range items {
+			// Removed tracks come back as null; local files have no ID but a name
+			track := item.Track.AsTrack()
+			if track.ID != "" || track.Name != "" {
+				tracks = append(tracks, track)
+			}
 		}
-		return nil, err
+		fetched += len(items)
+		emit(TrackBatch{Tracks: tracks, Total: total})
 	}
+	emitPage(playlist.Tracks.Items)
+
+	urls := pageURLs(withMarket(fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks?additional_types=track,episode&fields=%s",
+		playlistID, playlistPageFieldsParam), market),
+		len(playlist.Tracks.Items), total, 100)
+	err = fetchPages(ctx, c, urls, func(page *struct {
+		Items []PlaylistEntry `json:"items"`
+	}) {
+		emitPage(page.Items)
+	})
 
+	if err != nil {
+		return &PartialError{Kind: KindPlaylist, ID: playlistID, Total: total, Fetched: fetched, Err: err}
+	}
+	return nil
+}
+
+// GetPlaylist returns the playlist name, owner and images without its items.
+// Use GetPlaylistTracks for the tracks.
+func (c *Client) GetPlaylist(ctx context.Context, playlistID, market string) (*Playlist, error) {
 	var playlist Playlist
-	if err := json.Unmarshal(data, &playlist); err != nil {
-		return nil, err
+	err := c.fetchPage(ctx, withMarket(fmt.Sprintf("https://api.spotify.com/v1/playlists/%s?fields=%s",
+		playlistID, playlistInfoFieldsParam), c.marketFor(market)), &playlist)
+	if err != nil {
+		return nil, playlistError(playlistID, err)
 	}
+	return &playlist, nil
+}
 
-	var tracks []Track
-	for _, item := range playlist.Tracks.Items {
-		if item.Track.ID != "" && len(item.Track.Album.Images) > 0 {
-			tracks = append(tracks, item.Track)
+// playlistError explains a playlist that was not found: Spotify hides
+// editorial playlists from the Web API and private ones from other users.
+func playlistError(playlistID string, err error) error {
+	if apiErr, ok := AsAPIError(err); ok && apiErr.NotFound() {
+		if strings.HasPrefix(playlistID, "37i9dQZF") {
+			return ErrEditorialPlaylist
 		}
+		return fmt.Errorf("%w: %w", ErrPrivatePlaylist, err)
 	}
+	return err
+}
 
-	offset := 100
-	for offset < playlist.Tracks.Total {
-		url := fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks?offset=%d&limit=100", playlistID, offset)
-		pageData, err := c.apiRequest(ctx, url)
-		if err != nil {
-			break
-		}
+// GetArtistAlbums returns one entry per release of the artist, limited to the
+// given album groups. Entries carry album data only and have no track ID.
+func (c *Client) GetArtistAlbums(ctx context.Context, artistID string, groups []string, market string) ([]Track, error) {
+	return collectTracks(func(emit func(TrackBatch)) error {
+		return c.streamArtistAlbums(ctx, artistID, groups, c.marketFor(market), emit)
+	})
+}
 
-		var page struct {
-			Items []struct {
-				Track Track `json:"track"`
-			} `json:"items"`
-		}
-		if err := json.Unmarshal(pageData, &page); err != nil {
-			break
-		}
+func (c *Client) streamArtistAlbums(ctx context.Context, artistID string, groups []string, market string, emit func(TrackBatch)) error {
+	if len(groups) == 0 {
+		groups = DefaultAlbumGroups
+	}
+	base := withMarket(fmt.Sprintf("https://api.spotify.com/v1/artists/%s/albums?include_groups=%s", artistID, strings.Join(groups, ",")), market)
+
+	var first ArtistAlbums
+	if err := c.fetchPage(ctx, pageURL(base, 0, 50), &first); err != nil {
+		return err
+	}
 
+	total := first.Total
+	fetched := 0
+	emitPage := func(page *ArtistAlbums) {
+		tracks := make([]Track, 0, len(page.Items))
 		for _, item := range page.Items {
-			if item.Track.ID != "" && len(item.Track.Album.Images) > 0 {
-				tracks = append(tracks, item.Track)
+			if len(item.Images) == 0 {
+				continue
 			}
+			var track Track
+			track.Album = item
+			track.Name = item.Name
+			track.Artists = item.Artists
+			tracks = append(tracks, track)
 		}
+		fetched += len(page.Items)
+		emit(TrackBatch{Tracks: tracks, Total: total})
+	}
+	emitPage(&first)
 
-		offset += 100
+	err := fetchPages(ctx, c, pageURLs(base, len(first.Items), total, 50), emitPage)
+	if err != nil {
+		return &PartialError{Kind: KindArtist, ID: artistID, Total: total, Fetched: fetched, Err: err}
 	}
+	return nil
+}
+
+func (c *Client) GetEpisode(ctx context.Context, episodeID, market string) (*Track, error) {
+	apiURL := withMarket(fmt.Sprintf("https://api.spotify.com/v1/episodes/%s", episodeID), podcastMarket(c.marketFor(market)))
+
+	var episode Episode
+	if err := c.apiRequest(ctx, apiURL, &episode); err != nil {
+		return nil, err
+	}
+
+	track := episode.AsTrack()
+	return &track, nil
+}
 
-	return tracks, nil
+// GetShowEpisodes returns the show cover followed by one entry per episode.
+func (c *Client) GetShowEpisodes(ctx context.Context, showID, market string) ([]Track, error) {
+	return collectTracks(func(emit func(TrackBatch)) error {
+		return c.streamShowEpisodes(ctx, showID, c.marketFor(market), emit)
+	})
 }
 
-func (c *Client) GetTracks(ctx context.Context, url string) ([]Track, string, string, error) {
-	urlType := DetectURLType(url)
-	sourceID, err := ExtractID(url, urlType)
+func (c *Client) streamShowEpisodes(ctx context.Context, showID, market string, emit func(TrackBatch)) error {
+	market = podcastMarket(market)
+
+	var show Show
+	if err := c.fetchPage(ctx, withMarket(fmt.Sprintf("https://api.spotify.com/v1/shows/%s", showID), market), &show); err != nil {
+		return err
+	}
+
+	total := show.Episodes.Total
+	if len(show.Images) > 0 {
+		emit(TrackBatch{Tracks: []Track{show.cover()}, Total: total})
+	}
+
+	fetched := 0
+	emitPage := func(episodes []Episode) {
+		tracks := make([]Track, 0, len(episodes))
+		for _, episode := range episodes {
+			if episode.ID == "" || len(episode.Images) == 0 {
+				continue
+			}
+			episode.Show = show.EpisodeShow
+			tracks = append(tracks, episode.AsTrack())
+		}
+		fetched += len(episodes)
+		emit(TrackBatch{Tracks: tracks, Total: total})
+	}
+	emitPage(show.Episodes.Items)
+
+	urls := pageURLs(withMarket(fmt.Sprintf("https://api.spotify.com/v1/shows/%s/episodes", showID), market),
+		len(show.Episodes.Items), total, 50)
+	err := fetchPages(ctx, c, urls, func(page *struct {
+		Items []Episode `json:"items"`
+	}) {
+		emitPage(page.Items)
+	})
+
 	if err != nil {
-		return nil, "", "", err
+		return &PartialError{Kind: KindShow, ID: showID, Total: total, Fetched: fetched, Err: err}
 	}
+	return nil
+}
 
-	var tracks []Track
+// cover returns the entry that carries the show's own cover.
+func (show *Show) cover() Track {
+	var cover Track
+	show.describe(&cover.Album)
+	cover.Album.Images = show.Images
+	cover.Name = show.Name
+	cover.URI = show.URI
+	cover.Type = "show"
+	if show.Publisher != "" {
+		cover.Artists = []Artist{{Name: show.Publisher}}
+	}
+	return cover
+}
 
-	switch urlType {
-	case "track":
-		track, err := c.GetTrack(ctx, sourceID)
-		if err != nil {
-			return nil, "", "", err
+// GetCover returns an entry with the cover of a track, album, episode or show
+// link. Only the object itself is read, not the tracks or episodes after its
+// first page, so it's cheap for long albums and shows.
+func (c *Client) GetCover(ctx context.Context, link Link, market string) (*Track, error) {
+	market = c.marketFor(market)
+
+	switch link.Kind {
+	case KindTrack:
+		return c.GetTrack(ctx, link.ID, market)
+	case KindEpisode:
+		return c.GetEpisode(ctx, link.ID, market)
+	case KindAlbum:
+		var album Album
+		if err := c.fetchPage(ctx, withMarket(fmt.Sprintf("https://api.spotify.com/v1/albums/%s", link.ID), market), &album); err != nil {
+			return nil, err
+		}
+		cover := Track{Name: album.Name, URI: album.URI, Album: album.SimpleAlbum, Artists: album.Artists}
+		cover.Album.ID = link.ID
+		return &cover, nil
+	case KindShow:
+		var show Show
+		if err := c.fetchPage(ctx, withMarket(fmt.Sprintf("https://api.spotify.com/v1/shows/%s", link.ID), podcastMarket(market)), &show); err != nil {
+			return nil, err
 		}
-		tracks = []Track{*track}
-	case "album":
-		tracks, err = c.GetAlbumTracks(ctx, sourceID)
+		if len(show.Images) > 0 {
+			cover := show.cover()
+			return &cover, nil
+		}
+		// A show without its own cover: use the first episode that has one
+		for _, episode := range show.Episodes.Items {
+			if len(episode.Images) > 0 {
+				episode.Show = show.EpisodeShow
+				cover := episode.AsTrack()
+				return &cover, nil
+			}
+		}
+		return &Track{}, nil
+	}
+
+	return nil, ErrUnsupportedLink
+}
+
+// GetTracks expands a link into tracks. When a paged listing could only be
+// read in part, the tracks read so far are returned with a *PartialError.
+func (c *Client) GetTracks(ctx context.Context, link Link, opts FetchOptions) ([]Track, error) {
+	return collectTracks(func(emit func(TrackBatch)) error {
+		return c.streamTracks(ctx, link, opts, emit)
+	})
+}
+
+func (c *Client) streamTracks(ctx context.Context, link Link, opts FetchOptions, emit func(TrackBatch)) error {
+	market := c.marketFor(opts.Market)
+
+	switch link.Kind {
+	case KindTrack:
+		track, err := c.GetTrack(ctx, link.ID, market)
 		if err != nil {
-			return nil, "", "", err
+			return err
 		}
-	case "playlist":
-		tracks, err = c.GetPlaylistTracks(ctx, sourceID)
+		emit(TrackBatch{Tracks: []Track{*track}, Total: 1})
+		return nil
+	case KindAlbum:
+		return c.streamAlbumTracks(ctx, link.ID, market, emit)
+	case KindPlaylist:
+		return c.streamPlaylistTracks(ctx, link.ID, market, emit)
+	case KindEpisode:
+		track, err := c.GetEpisode(ctx, link.ID, market)
 		if err != nil {
-			return nil, "", "", err
+			return err
 		}
-	default:
-		return nil, "", "", fmt.Errorf("unsupported URL type")
+		emit(TrackBatch{Tracks: []Track{*track}, Total: 1})
+		return nil
+	case KindShow:
+		return c.streamShowEpisodes(ctx, link.ID, market, emit)
+	case KindArtist:
+		return c.streamArtistAlbums(ctx, link.ID, opts.AlbumGroups, market, emit)
 	}
 
-	return tracks, sourceID, urlType, nil
+	return ErrUnsupportedLink
 }
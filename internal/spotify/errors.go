@@ -0,0 +1,104 @@
+package spotify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrEditorialPlaylist = errors.New("editorial playlists are not accessible via API")
+	ErrPrivatePlaylist   = errors.New("playlist is private or does not exist")
+)
+
+// APIError is a non-200 reply from the Spotify Web API.
+type APIError struct {
+	Status     int
+	Message    string
+	RetryAfter time.Duration // from the Retry-After header, 0 if absent
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("spotify API status %d", e.Status)
+	}
+	return fmt.Sprintf("spotify API status %d: %s", e.Status, e.Message)
+}
+
+func (e *APIError) NotFound() bool {
+	return e.Status == http.StatusNotFound
+}
+
+func (e *APIError) RateLimited() bool {
+	return e.Status == http.StatusTooManyRequests
+}
+
+// ServerError reports a 5xx reply, i.e. Spotify itself is having trouble.
+func (e *APIError) ServerError() bool {
+	return e.Status >= 500
+}
+
+// Temporary reports whether the request is worth retrying.
+func (e *APIError) Temporary() bool {
+	return e.RateLimited() || e.ServerError()
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{Status: resp.StatusCode}
+
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error.Message != "" {
+		apiErr.Message = payload.Error.Message
+	} else if len(body) > 0 && len(body) < 200 {
+		apiErr.Message = string(body)
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return apiErr
+}
+
+// AsAPIError unwraps err into an *APIError if there is one.
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// PartialError reports a paged listing that could only be read in part.
+// It is returned together with the items that were read.
+type PartialError struct {
+	Kind    LinkKind
+	ID      string
+	Total   int // items Spotify reported
+	Fetched int // items actually read
+	Err     error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%s %s read partially (%d of %d items): %v", e.Kind, e.ID, e.Fetched, e.Total, e.Err)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// AsPartialError unwraps err into a *PartialError if there is one.
+func AsPartialError(err error) (*PartialError, bool) {
+	var partialErr *PartialError
+	if errors.As(err, &partialErr) {
+		return partialErr, true
+	}
+	return nil, false
+}
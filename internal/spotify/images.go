@@ -0,0 +1,115 @@
+package spotify
+
+import (
+	"errors"
+	"strings"
+)
+
+// Resolution selects which size of a cover is downloaded
+type Resolution string
+
+const (
+	ResolutionThumbnail Resolution = "thumbnail" // 64px
+	ResolutionStandard  Resolution = "standard"  // 300px
+	ResolutionLargest   Resolution = "largest"   // largest size the API lists, usually 640px
+	ResolutionOriginal  Resolution = "original"  // full-size upload from the image CDN
+)
+
+// ErrImageNotFound is returned by Downloader when the CDN has no image at the URL.
+var ErrImageNotFound = errors.New("image not found")
+
+// Album covers on i.scdn.co are addressed as <prefix><size code><hash>.
+// Swapping the size code gives the other renditions of the same cover.
+const (
+	albumImagePrefix   = "ab67616d"
+	albumSizeCodeLen   = 8
+	originalSizeCode   = "000082c1"
+	scdnImagePathStart = "https://i.scdn.co/image/"
+)
+
+var resolutionAliases = map[string]Resolution{
+	"thumbnail": ResolutionThumbnail,
+	"thumb":     ResolutionThumbnail,
+	"small":     ResolutionThumbnail,
+	"64":        ResolutionThumbnail,
+	"standard":  ResolutionStandard,
+	"medium":    ResolutionStandard,
+	"300":       ResolutionStandard,
+	"largest":   ResolutionLargest,
+	"large":     ResolutionLargest,
+	"640":       ResolutionLargest,
+	"original":  ResolutionOriginal,
+	"orig":      ResolutionOriginal,
+	"full":      ResolutionOriginal,
+	"max":       ResolutionOriginal,
+}
+
+// ParseResolution parses a resolution name such as "original" or "300".
+func ParseResolution(value string) (Resolution, bool) {
+	res, ok := resolutionAliases[strings.ToLower(strings.TrimSpace(value))]
+	return res, ok
+}
+
+// PickImage returns the URL of the cover in the requested resolution.
+// fallback is set when the URL is not listed by the API and may not exist;
+// it points to the largest listed image.
+func PickImage(images []Image, res Resolution) (imageURL, fallback string) {
+	if len(images) == 0 {
+		return "", ""
+	}
+
+	smallest, largest := images[0], images[0]
+	for _, img := range images[1:] {
+		if img.Width < smallest.Width {
+			smallest = img
+		}
+		if img.Width > largest.Width {
+			largest = img
+		}
+	}
+
+	switch res {
+	case ResolutionThumbnail:
+		return smallest.URL, ""
+	case ResolutionStandard:
+		best := largest
+		for _, img := range images {
+			if img.Width >= 300 && img.Width < best.Width {
+				best = img
+			}
+		}
+		return best.URL, ""
+	case ResolutionOriginal:
+		if original, ok := originalImageURL(largest.URL); ok {
+			return original, largest.URL
+		}
+		return largest.URL, ""
+	default:
+		return largest.URL, ""
+	}
+}
+
+// originalImageURL rewrites an album cover URL to the full-size rendition.
+// Only album covers follow the known scheme; other images are left as is.
+func originalImageURL(imageURL string) (string, bool) {
+	hash, ok := strings.CutPrefix(imageURL, scdnImagePathStart)
+	if !ok || !strings.HasPrefix(hash, albumImagePrefix) || len(hash) <= len(albumImagePrefix)+albumSizeCodeLen {
+		return "", false
+	}
+	rest := hash[len(albumImagePrefix)+albumSizeCodeLen:]
+	return scdnImagePathStart + albumImagePrefix + originalSizeCode + rest, true
+}
+
+// ImageID returns the ID of an image on Spotify's CDNs: the last element of
+// its URL path, e.g. "ab67616d0000b273…" for an album cover. It is "" when
+// the URL doesn't end in a plain ID.
+func ImageID(imageURL string) string {
+	path, _, _ := strings.Cut(imageURL, "?")
+	id := path[strings.LastIndex(path, "/")+1:]
+	for _, r := range id {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return ""
+		}
+	}
+	return id
+}
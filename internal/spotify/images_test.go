@@ -0,0 +1,81 @@
+package spotify
+
+import "testing"
+
+func TestPickImage(t *testing.T) {
+	images := []Image{
+		{URL: "https://i.scdn.co/image/ab67616d0000b2731234", Width: 640},
+		{URL: "https://i.scdn.co/image/ab67616d00001e021234", Width: 300},
+		{URL: "https://i.scdn.co/image/ab67616d000048511234", Width: 64},
+	}
+
+	tests := []struct {
+		name     string
+		images   []Image
+		res      Resolution
+		url      string
+		fallback string
+	}{
+		{name: "thumbnail", images: images, res: ResolutionThumbnail, url: "https://i.scdn.co/image/ab67616d000048511234"},
+		{name: "standard", images: images, res: ResolutionStandard, url: "https://i.scdn.co/image/ab67616d00001e021234"},
+		{name: "largest", images: images, res: ResolutionLargest, url: "https://i.scdn.co/image/ab67616d0000b2731234"},
+		{name: "empty resolution", images: images, res: "", url: "https://i.scdn.co/image/ab67616d0000b2731234"},
+		{
+			name:     "original album cover",
+			images:   images,
+			res:      ResolutionOriginal,
+			url:      "https://i.scdn.co/image/ab67616d000082c11234",
+			fallback: "https://i.scdn.co/image/ab67616d0000b2731234",
+		},
+		{
+			name:   "original of other image",
+			images: []Image{{URL: "https://i.scdn.co/image/ab6765630000ba8a1234", Width: 640}},
+			res:    ResolutionOriginal,
+			url:    "https://i.scdn.co/image/ab6765630000ba8a1234",
+		},
+		{
+			name:   "standard without a 300px image",
+			images: []Image{{URL: "https://mosaic.scdn.co/640/a", Width: 640}, {URL: "https://mosaic.scdn.co/60/a", Width: 60}},
+			res:    ResolutionStandard,
+			url:    "https://mosaic.scdn.co/640/a",
+		},
+		{
+			name:   "unsorted images",
+			images: []Image{images[1], images[2], images[0]},
+			res:    ResolutionLargest,
+			url:    "https://i.scdn.co/image/ab67616d0000b2731234",
+		},
+		{name: "no images", images: nil, res: ResolutionLargest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, fallback := PickImage(tt.images, tt.res)
+			if url != tt.url || fallback != tt.fallback {
+				t.Errorf("PickImage = (%q, %q), want (%q, %q)", url, fallback, tt.url, tt.fallback)
+			}
+		})
+	}
+}
+
+func TestOriginalImageURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+		ok   bool
+	}{
+		{url: "https://i.scdn.co/image/ab67616d0000b273abcdef", want: "https://i.scdn.co/image/ab67616d000082c1abcdef", ok: true},
+		{url: "https://i.scdn.co/image/ab67616d00004851abcdef", want: "https://i.scdn.co/image/ab67616d000082c1abcdef", ok: true},
+		{url: "https://i.scdn.co/image/ab67616d0000b273"},       // no hash after the size code
+		{url: "https://i.scdn.co/image/ab6761610000e5ebabcdef"}, // artist image
+		{url: "https://mosaic.scdn.co/640/ab67616d0000b273abcdef"},
+		{url: ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := originalImageURL(tt.url)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("originalImageURL(%q) = (%q, %v), want (%q, %v)", tt.url, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type LinkKind string
+
+const (
+	KindTrack    LinkKind = "track"
+	KindAlbum    LinkKind = "album"
+	KindPlaylist LinkKind = "playlist"
+	KindArtist   LinkKind = "artist"
+	KindShow     LinkKind = "show"
+	KindEpisode  LinkKind = "episode"
+)
+
+var knownKinds = map[LinkKind]bool{
+	KindTrack:    true,
+	KindAlbum:    true,
+	KindPlaylist: true,
+	KindArtist:   true,
+	KindShow:     true,
+	KindEpisode:  true,
+}
+
+var ErrUnsupportedLink = errors.New("unsupported Spotify link")
+
+// Link is a parsed Spotify link: open.spotify.com URLs (including /intl-xx/
+// and /embed/ paths), spotify: URIs and resolved short links.
+type Link struct {
+	Kind LinkKind
+	ID   string
+	Raw  string // text the link was parsed from
+}
+
+// URL returns the canonical open.spotify.com URL of the link.
+func (l Link) URL() string {
+	return fmt.Sprintf("https://open.spotify.com/%s/%s", l.Kind, l.ID)
+}
+
+// Resolver follows short links (spotify.link, spoti.fi) to their target URL.
+type Resolver interface {
+	Resolve(ctx context.Context, shortURL string) (string, error)
+}
+
+var (
+	idPattern = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+	// linkPattern finds link candidates in free text; ParseLink validates them.
+	linkPattern = regexp.MustCompile(
+		`spotify:(?:user:[^:\s]+:)?[a-z]+:[a-zA-Z0-9]+` +
+			`|(?:https?://)?(?:open\.spotify\.com|play\.spotify\.com|spotify\.link|spoti\.fi)/[^\s<>"']+`)
+
+	openURLPattern = regexp.MustCompile(`https://open\.spotify\.com/[^\s<>"'\\]+`)
+)
+
+// FindLinks returns every Spotify link candidate in text, in order.
+func FindLinks(text string) []string {
+	matches := linkPattern.FindAllString(text, -1)
+	for i, match := range matches {
+		matches[i] = strings.TrimRight(match, ".,;:!?)]}")
+	}
+	return matches
+}
+
+// ParseLink parses a Spotify URL, URI or short link. Short links are
+// followed with resolver; a nil resolver rejects them.
+func ParseLink(ctx context.Context, raw string, resolver Resolver) (Link, error) {
+	raw = strings.TrimSpace(raw)
+
+	if strings.HasPrefix(raw, "spotify:") {
+		return parseURI(raw)
+	}
+
+	rawURL := raw
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Link{}, ErrUnsupportedLink
+	}
+
+	switch strings.ToLower(u.Host) {
+	case "open.spotify.com", "play.spotify.com":
+		link, err := parsePath(u.Path)
+		link.Raw = raw
+		return link, err
+	case "spotify.link", "spoti.fi":
+		if resolver == nil {
+			return Link{}, ErrUnsupportedLink
+		}
+		target, err := resolver.Resolve(ctx, u.String())
+		if err != nil {
+			return Link{}, fmt.Errorf("failed to resolve short link: %w", err)
+		}
+		link, err := ParseLink(ctx, target, nil)
+		link.Raw = raw
+		return link, err
+	}
+
+	return Link{}, ErrUnsupportedLink
+}
+
+// parseURI handles spotify:album:ID and the legacy spotify:user:NAME:playlist:ID.
+func parseURI(raw string) (Link, error) {
+	parts := strings.Split(raw, ":")
+	if len(parts) == 5 && parts[1] == "user" {
+		parts = []string{parts[0], parts[3], parts[4]}
+	}
+	if len(parts) != 3 {
+		return Link{}, ErrUnsupportedLink
+	}
+
+	kind := LinkKind(parts[1])
+	if !knownKinds[kind] || !idPattern.MatchString(parts[2]) {
+		return Link{}, ErrUnsupportedLink
+	}
+	return Link{Kind: kind, ID: parts[2], Raw: raw}, nil
+}
+
+// parsePath finds the "<kind>/<id>" pair in paths like /intl-de/album/ID,
+// /embed/playlist/ID or /user/NAME/playlist/ID.
+func parsePath(path string) (Link, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		kind := LinkKind(segments[i])
+		if knownKinds[kind] && idPattern.MatchString(segments[i+1]) {
+			return Link{Kind: kind, ID: segments[i+1]}, nil
+		}
+	}
+	return Link{}, ErrUnsupportedLink
+}
+
+// HTTPResolver resolves short links by following their redirects until
+// an open.spotify.com URL shows up.
+type HTTPResolver struct {
+	httpClient *http.Client
+}
+
+func NewHTTPResolver(timeout time.Duration) *HTTPResolver {
+	return &HTTPResolver{
+		httpClient: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if req.URL.Host == "open.spotify.com" {
+					return http.ErrUseLastResponse
+				}
+				if len(via) >= 10 {
+					return errors.New("too many redirects")
+				}
+				return nil
+			},
+		},
+	}
+}
+
+func (r *HTTPResolver) Resolve(ctx context.Context, shortURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, shortURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if location := resp.Header.Get("Location"); location != "" {
+		target, err := resp.Request.URL.Parse(location)
+		if err != nil {
+			return "", err
+		}
+		return target.String(), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+
+	// Some short links answer with an HTML page that redirects via script
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512*1024))
+	if target := openURLPattern.FindString(string(body)); target != "" {
+		return target, nil
+	}
+
+	return "", fmt.Errorf("no Spotify URL behind %s", shortURL)
+}
@@ -0,0 +1,126 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeResolver resolves short links from a map instead of the network.
+type fakeResolver map[string]string
+
+func (r fakeResolver) Resolve(ctx context.Context, shortURL string) (string, error) {
+	if target, ok := r[shortURL]; ok {
+		return target, nil
+	}
+	return "", errors.New("not found")
+}
+
+func TestParseLink(t *testing.T) {
+	resolver := fakeResolver{
+		"https://spotify.link/abc123": "https://open.spotify.com/album/4aawyAB9vmqN3uQ7FjRGTy?si=xyz",
+		"https://spoti.fi/3xYz":       "https://open.spotify.com/intl-de/track/6rqhFgbbKwnb9MLmUQDhG6",
+		"https://spotify.link/nested": "https://spotify.link/abc123",
+	}
+
+	tests := []struct {
+		name string
+		raw  string
+		kind LinkKind
+		id   string
+		err  bool
+	}{
+		{name: "track URL", raw: "https://open.spotify.com/track/6rqhFgbbKwnb9MLmUQDhG6", kind: KindTrack, id: "6rqhFgbbKwnb9MLmUQDhG6"},
+		{name: "query string", raw: "https://open.spotify.com/album/4aawyAB9vmqN3uQ7FjRGTy?si=abc", kind: KindAlbum, id: "4aawyAB9vmqN3uQ7FjRGTy"},
+		{name: "no scheme", raw: "open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M", kind: KindPlaylist, id: "37i9dQZF1DXcBWIGoYBM5M"},
+		{name: "localized path", raw: "https://open.spotify.com/intl-ja/artist/0OdUWJ0sBjDrqHygGUXeCF", kind: KindArtist, id: "0OdUWJ0sBjDrqHygGUXeCF"},
+		{name: "embed path", raw: "https://open.spotify.com/embed/playlist/37i9dQZF1DXcBWIGoYBM5M", kind: KindPlaylist, id: "37i9dQZF1DXcBWIGoYBM5M"},
+		{name: "user playlist path", raw: "https://open.spotify.com/user/spotify/playlist/37i9dQZF1DXcBWIGoYBM5M", kind: KindPlaylist, id: "37i9dQZF1DXcBWIGoYBM5M"},
+		{name: "play host", raw: "https://play.spotify.com/show/5CfCWKI5pZ28U0uOzXkDHe", kind: KindShow, id: "5CfCWKI5pZ28U0uOzXkDHe"},
+		{name: "album URI", raw: "spotify:album:4aawyAB9vmqN3uQ7FjRGTy", kind: KindAlbum, id: "4aawyAB9vmqN3uQ7FjRGTy"},
+		{name: "episode URI", raw: "spotify:episode:512ojhOuo1ktJprKbVcKyQ", kind: KindEpisode, id: "512ojhOuo1ktJprKbVcKyQ"},
+		{name: "legacy user playlist URI", raw: "spotify:user:spotify:playlist:37i9dQZF1DXcBWIGoYBM5M", kind: KindPlaylist, id: "37i9dQZF1DXcBWIGoYBM5M"},
+		{name: "spotify.link short link", raw: "https://spotify.link/abc123", kind: KindAlbum, id: "4aawyAB9vmqN3uQ7FjRGTy"},
+		{name: "spoti.fi short link", raw: "spoti.fi/3xYz", kind: KindTrack, id: "6rqhFgbbKwnb9MLmUQDhG6"},
+		{name: "short link to short link", raw: "https://spotify.link/nested", err: true},
+		{name: "unresolved short link", raw: "https://spotify.link/missing", err: true},
+		{name: "unknown kind URI", raw: "spotify:user:spotify", err: true},
+		{name: "unknown kind path", raw: "https://open.spotify.com/genre/pop", err: true},
+		{name: "bad ID", raw: "spotify:track:not-an-id", err: true},
+		{name: "other host", raw: "https://example.com/track/6rqhFgbbKwnb9MLmUQDhG6", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := ParseLink(context.Background(), tt.raw, resolver)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("ParseLink(%q) = %+v, want error", tt.raw, link)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLink(%q) error: %v", tt.raw, err)
+			}
+			if link.Kind != tt.kind || link.ID != tt.id {
+				t.Errorf("ParseLink(%q) = %s/%s, want %s/%s", tt.raw, link.Kind, link.ID, tt.kind, tt.id)
+			}
+			if link.Raw != tt.raw {
+				t.Errorf("ParseLink(%q).Raw = %q", tt.raw, link.Raw)
+			}
+		})
+	}
+}
+
+func TestParseLinkWithoutResolver(t *testing.T) {
+	if _, err := ParseLink(context.Background(), "https://spotify.link/abc123", nil); !errors.Is(err, ErrUnsupportedLink) {
+		t.Errorf("short link without resolver: err = %v, want ErrUnsupportedLink", err)
+	}
+}
+
+func TestFindLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "single link",
+			text: "https://open.spotify.com/album/4aawyAB9vmqN3uQ7FjRGTy",
+			want: []string{"https://open.spotify.com/album/4aawyAB9vmqN3uQ7FjRGTy"},
+		},
+		{
+			name: "trailing punctuation",
+			text: "Check this out (https://open.spotify.com/track/6rqhFgbbKwnb9MLmUQDhG6)! And spotify:album:4aawyAB9vmqN3uQ7FjRGTy.",
+			want: []string{"https://open.spotify.com/track/6rqhFgbbKwnb9MLmUQDhG6", "spotify:album:4aawyAB9vmqN3uQ7FjRGTy"},
+		},
+		{
+			name: "options after link",
+			text: "https://open.spotify.com/artist/0OdUWJ0sBjDrqHygGUXeCF?si=abc groups=album market=JP",
+			want: []string{"https://open.spotify.com/artist/0OdUWJ0sBjDrqHygGUXeCF?si=abc"},
+		},
+		{
+			name: "mixed forms in order",
+			text: "spoti.fi/3xYz, https://open.spotify.com/intl-de/album/4aawyAB9vmqN3uQ7FjRGTy; spotify:user:spotify:playlist:37i9dQZF1DXcBWIGoYBM5M",
+			want: []string{"spoti.fi/3xYz", "https://open.spotify.com/intl-de/album/4aawyAB9vmqN3uQ7FjRGTy", "spotify:user:spotify:playlist:37i9dQZF1DXcBWIGoYBM5M"},
+		},
+		{
+			name: "no links",
+			text: "hello https://example.com",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindLinks(tt.text)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindLinks(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
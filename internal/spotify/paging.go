@@ -0,0 +1,164 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const maxPageAttempts = 3
+
+// TrackBatch is one page worth of tracks, delivered in listing order.
+type TrackBatch struct {
+	Tracks []Track
+	Total  int   // items in the whole listing, as reported by Spotify
+	Err    error // set on the last batch if the listing failed or was read partially
+}
+
+// StreamTracks expands a link like GetTracks, but hands out tracks page by
+// page as they arrive. The channel is closed when the listing is done; a
+// failure (including *PartialError) arrives as a final batch with Err set.
+func (c *Client) StreamTracks(ctx context.Context, link Link, opts FetchOptions) <-chan TrackBatch {
+	batches := make(chan TrackBatch, 1)
+
+	go func() {
+		defer close(batches)
+
+		send := func(batch TrackBatch) {
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+			}
+		}
+
+		if err := c.streamTracks(ctx, link, opts, send); err != nil {
+			send(TrackBatch{Err: err})
+		}
+	}()
+
+	return batches
+}
+
+// collectTracks gathers a streamed listing into one slice.
+func collectTracks(stream func(emit func(TrackBatch)) error) ([]Track, error) {
+	var tracks []Track
+	err := stream(func(batch TrackBatch) {
+		tracks = append(tracks, batch.Tracks...)
+	})
+	return tracks, err
+}
+
+// fetchPage loads one page of a paged listing into v. Network failures and
+// replies that break off mid-decode are retried here; apiRequest already
+// retries 429 and 5xx.
+func (c *Client) fetchPage(ctx context.Context, url string, v interface{}) error {
+	var err error
+	for attempt := 0; attempt < maxPageAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		err = c.apiRequest(ctx, url, v)
+		if err == nil {
+			return nil
+		}
+		if apiErr, ok := AsAPIError(err); ok && !apiErr.Temporary() {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Debug().Err(err).Str("url", url).Int("attempt", attempt+1).Msg("Page fetch failed")
+	}
+	return err
+}
+
+// pageURL adds paging parameters to a listing URL.
+func pageURL(base string, offset, limit int) string {
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%soffset=%d&limit=%d", base, separator, offset, limit)
+}
+
+// pageURLs lists the URLs of the pages following the first one.
+func pageURLs(base string, first, total, limit int) []string {
+	var urls []string
+	for offset := first; offset < total; offset += limit {
+		urls = append(urls, pageURL(base, offset, limit))
+	}
+	return urls
+}
+
+type pageResult[T any] struct {
+	index int
+	page  *T
+	err   error
+}
+
+// fetchPages loads pages concurrently, at most c.pageConcurrency at a time,
+// and passes each to onPage in page order as soon as the pages before it are
+// done. Failed pages are skipped; the first failure in page order is returned.
+func fetchPages[T any](ctx context.Context, c *Client, urls []string, onPage func(*T)) error {
+	results := make(chan pageResult[T])
+	// Don't run too far ahead of the page that is due next
+	window := c.pageConcurrency * 4
+
+	launched, inFlight, next := 0, 0, 0
+	launch := func() {
+		for launched < len(urls) && inFlight < c.pageConcurrency && launched-next < window {
+			index := launched
+			go func() {
+				var page T
+				if err := c.fetchPage(ctx, urls[index], &page); err != nil {
+					results <- pageResult[T]{index: index, err: err}
+					return
+				}
+				results <- pageResult[T]{index: index, page: &page}
+			}()
+			launched++
+			inFlight++
+		}
+	}
+
+	ready := make(map[int]pageResult[T])
+	var firstErr error
+
+	launch()
+	for next < len(urls) {
+		result := <-results
+		inFlight--
+		ready[result.index] = result
+
+		for {
+			due, ok := ready[next]
+			if !ok {
+				break
+			}
+			delete(ready, next)
+			next++
+
+			if due.err != nil {
+				if firstErr == nil {
+					firstErr = due.err
+				}
+				continue
+			}
+			onPage(due.page)
+		}
+
+		launch()
+	}
+
+	return firstErr
+}
@@ -0,0 +1,97 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testPage struct {
+	Offset int `json:"offset"`
+}
+
+// newPagingTestClient returns a client with a valid token whose page
+// requests go to a server that answers with the offset of the page after a
+// random delay, so pages complete out of order. Offsets in missing get a 404.
+func newPagingTestClient(t *testing.T, concurrency int, missing map[int]bool) (*Client, string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+		if missing[offset] {
+			http.Error(w, `{"error":{"status":404,"message":"Not found"}}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"offset":%d}`, offset)
+	}))
+	t.Cleanup(server.Close)
+
+	c := NewClient("id", "secret", concurrency, "")
+	c.accessToken = "token"
+	c.tokenExpiry = time.Now().Add(time.Hour)
+	return c, server.URL + "/pages"
+}
+
+func TestFetchPagesOrder(t *testing.T) {
+	for _, concurrency := range []int{1, 3, 8} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			c, base := newPagingTestClient(t, concurrency, nil)
+			urls := pageURLs(base, 50, 2050, 50)
+
+			var offsets []int
+			err := fetchPages(context.Background(), c, urls, func(page *testPage) {
+				offsets = append(offsets, page.Offset)
+			})
+			if err != nil {
+				t.Fatalf("fetchPages error: %v", err)
+			}
+			if len(offsets) != len(urls) {
+				t.Fatalf("got %d pages, want %d", len(offsets), len(urls))
+			}
+			for i, offset := range offsets {
+				if want := 50 + i*50; offset != want {
+					t.Fatalf("page %d has offset %d, want %d", i, offset, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchPagesFailure(t *testing.T) {
+	c, base := newPagingTestClient(t, 4, map[int]bool{150: true, 400: true})
+	urls := pageURLs(base, 50, 550, 50)
+
+	var offsets []int
+	err := fetchPages(context.Background(), c, urls, func(page *testPage) {
+		offsets = append(offsets, page.Offset)
+	})
+
+	apiErr, ok := AsAPIError(err)
+	if !ok || apiErr.Status != http.StatusNotFound {
+		t.Fatalf("err = %v, want the 404 of the first failed page", err)
+	}
+	want := []int{50, 100, 200, 250, 300, 350, 450, 500}
+	if fmt.Sprint(offsets) != fmt.Sprint(want) {
+		t.Errorf("pages = %v, want %v: failed pages are skipped, the rest keep their order", offsets, want)
+	}
+}
+
+func TestPageURLs(t *testing.T) {
+	urls := pageURLs("https://api.spotify.com/v1/albums/x/tracks?market=JP", 50, 160, 50)
+	want := []string{
+		"https://api.spotify.com/v1/albums/x/tracks?market=JP&offset=50&limit=50",
+		"https://api.spotify.com/v1/albums/x/tracks?market=JP&offset=100&limit=50",
+		"https://api.spotify.com/v1/albums/x/tracks?market=JP&offset=150&limit=50",
+	}
+	if fmt.Sprint(urls) != fmt.Sprint(want) {
+		t.Errorf("pageURLs = %q, want %q", urls, want)
+	}
+	if urls := pageURLs("https://api.spotify.com/v1/x", 50, 50, 50); len(urls) != 0 {
+		t.Errorf("single page listing: pageURLs = %q, want none", urls)
+	}
+}
@@ -1,28 +1,112 @@
 package spotify
 
+import (
+	"image/color"
+	"time"
+)
+
+type Image struct {
+	URL    string `json:"url"`
+	Height int    `json:"height"`
+	Width  int    `json:"width"`
+}
+
+type ExternalURLs struct {
+	Spotify string `json:"spotify"`
+}
+
+type ExternalIDs struct {
+	ISRC string `json:"isrc"`
+	UPC  string `json:"upc"`
+	EAN  string `json:"ean"`
+}
+
+type Artist struct {
+	Name         string       `json:"name"`
+	ID           string       `json:"id"`
+	URI          string       `json:"uri"`
+	ExternalURLs ExternalURLs `json:"external_urls"`
+}
+
+// SimpleAlbum is the album object embedded in tracks and artist listings.
+// For episodes it describes the show.
+type SimpleAlbum struct {
+	Images               []Image      `json:"images"`
+	Name                 string       `json:"name"`
+	ID                   string       `json:"id"`
+	URI                  string       `json:"uri"`
+	AlbumType            string       `json:"album_type"`
+	AlbumGroup           string       `json:"album_group"` // only in artist listings
+	TotalTracks          int          `json:"total_tracks"`
+	ReleaseDate          string       `json:"release_date"`
+	ReleaseDatePrecision string       `json:"release_date_precision"`
+	Artists              []Artist     `json:"artists"`
+	ExternalURLs         ExternalURLs `json:"external_urls"`
+
+	// Only in full album objects
+	Label       string      `json:"label"`
+	ExternalIDs ExternalIDs `json:"external_ids"`
+}
+
+// Year returns the release year, or "" when it is unknown.
+func (a SimpleAlbum) Year() string {
+	if len(a.ReleaseDate) < 4 || a.ReleaseDate[:4] == "0000" {
+		return ""
+	}
+	return a.ReleaseDate[:4]
+}
+
 type Track struct {
-	Album struct {
-		Images []struct {
-			URL    string `json:"url"`
-			Height int    `json:"height"`
-			Width  int    `json:"width"`
-		} `json:"images"`
-		Name string `json:"name"`
-	} `json:"album"`
-	Name    string `json:"name"`
-	Artists []struct {
-		Name string `json:"name"`
-	} `json:"artists"`
-	ID string `json:"id"`
+	Album        SimpleAlbum  `json:"album"`
+	Name         string       `json:"name"`
+	Artists      []Artist     `json:"artists"`
+	ID           string       `json:"id"`
+	URI          string       `json:"uri"`
+	Type         string       `json:"type"`
+	DiscNumber   int          `json:"disc_number"`
+	TrackNumber  int          `json:"track_number"`
+	DurationMs   int          `json:"duration_ms"`
+	Explicit     bool         `json:"explicit"`
+	ExternalIDs  ExternalIDs  `json:"external_ids"` // not returned for album track listings
+	ExternalURLs ExternalURLs `json:"external_urls"`
+
+	// Only set when the request named a market
+	IsPlayable *bool `json:"is_playable"`
+	LinkedFrom *struct {
+		ID string `json:"id"`
+	} `json:"linked_from"`
+}
+
+// OriginalID returns the ID the track was requested by. When Spotify relinks
+// a track to a version playable in the market, ID is the replacement.
+func (t Track) OriginalID() string {
+	if t.LinkedFrom != nil && t.LinkedFrom.ID != "" {
+		return t.LinkedFrom.ID
+	}
+	return t.ID
+}
+
+// Duration returns the track length.
+func (t Track) Duration() time.Duration {
+	return time.Duration(t.DurationMs) * time.Millisecond
+}
+
+// CoverArtists returns the artists credited on the cover: the album artists,
+// or the track artists when the album has none (episodes, local files).
+func (t Track) CoverArtists() []Artist {
+	if len(t.Album.Artists) > 0 {
+		return t.Album.Artists
+	}
+	return t.Artists
+}
+
+// Unavailable reports a track that cannot be played in the requested market.
+func (t Track) Unavailable() bool {
+	return t.IsPlayable != nil && !*t.IsPlayable
 }
 
 type Album struct {
-	Images []struct {
-		URL    string `json:"url"`
-		Height int    `json:"height"`
-		Width  int    `json:"width"`
-	} `json:"images"`
-	Name   string `json:"name"`
+	SimpleAlbum
 	Tracks struct {
 		Items []Track `json:"items"`
 		Next  string  `json:"next"`
@@ -31,22 +115,153 @@ type Album struct {
 }
 
 type Playlist struct {
-	Images []struct {
-		URL string `json:"url"`
-	} `json:"images"`
-	Name   string `json:"name"`
-	Tracks struct {
-		Items []struct {
-			Track Track `json:"track"`
-		} `json:"items"`
-		Next  string `json:"next"`
-		Total int    `json:"total"`
+	Images      []Image `json:"images"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	URI         string  `json:"uri"`
+	Owner       struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	} `json:"owner"`
+	ExternalURLs ExternalURLs `json:"external_urls"`
+	Tracks       struct {
+		Items []PlaylistEntry `json:"items"`
+		Next  string          `json:"next"`
+		Total int             `json:"total"`
 	} `json:"tracks"`
 }
 
+type PlaylistEntry struct {
+	Track PlaylistItem `json:"track"`
+}
+
+// PlaylistItem is a playlist entry, which is either a track or a podcast episode.
+type PlaylistItem struct {
+	Track
+	Images      []Image     `json:"images"`
+	ReleaseDate string      `json:"release_date"`
+	Show        EpisodeShow `json:"show"`
+}
+
+// AsTrack maps episodes onto the Track shape, with the episode cover as album art.
+func (p PlaylistItem) AsTrack() Track {
+	track := p.Track
+	if track.Type != "episode" {
+		return track
+	}
+
+	track.Album.Images = p.Images
+	if len(track.Album.Images) == 0 {
+		track.Album.Images = p.Show.Images
+	}
+	p.Show.describe(&track.Album)
+	track.Album.ReleaseDate = p.ReleaseDate
+	if p.Show.Publisher != "" {
+		track.Artists = []Artist{{Name: p.Show.Publisher}}
+	}
+	return track
+}
+
+// ArtistAlbums is a page of the artists/{id}/albums endpoint.
+type ArtistAlbums struct {
+	Items []SimpleAlbum `json:"items"`
+	Next  string        `json:"next"`
+	Total int           `json:"total"`
+}
+
+// EpisodeShow is the show object embedded in episodes.
+type EpisodeShow struct {
+	Images       []Image      `json:"images"`
+	Name         string       `json:"name"`
+	ID           string       `json:"id"`
+	URI          string       `json:"uri"`
+	Publisher    string       `json:"publisher"`
+	ExternalURLs ExternalURLs `json:"external_urls"`
+}
+
+// describe fills the album fields of an episode track from the show.
+func (s EpisodeShow) describe(album *SimpleAlbum) {
+	album.Name = s.Name
+	album.ID = s.ID
+	album.URI = s.URI
+	album.AlbumType = "show"
+	album.ExternalURLs = s.ExternalURLs
+}
+
+type Episode struct {
+	Images       []Image      `json:"images"`
+	Name         string       `json:"name"`
+	ID           string       `json:"id"`
+	URI          string       `json:"uri"`
+	ReleaseDate  string       `json:"release_date"`
+	DurationMs   int          `json:"duration_ms"`
+	Explicit     bool         `json:"explicit"`
+	ExternalURLs ExternalURLs `json:"external_urls"`
+	Show         EpisodeShow  `json:"show"`
+}
+
+// AsTrack maps an episode onto the Track shape, with the episode cover as album art.
+func (e Episode) AsTrack() Track {
+	var track Track
+	e.Show.describe(&track.Album)
+	track.Album.Images = e.Images
+	track.Album.ReleaseDate = e.ReleaseDate
+	track.Name = e.Name
+	track.ID = e.ID
+	track.URI = e.URI
+	track.Type = "episode"
+	track.DurationMs = e.DurationMs
+	track.Explicit = e.Explicit
+	track.ExternalURLs = e.ExternalURLs
+	if e.Show.Publisher != "" {
+		track.Artists = []Artist{{Name: e.Show.Publisher}}
+	}
+	return track
+}
+
+type Show struct {
+	EpisodeShow
+	Episodes struct {
+		Items []Episode `json:"items"`
+		Next  string    `json:"next"`
+		Total int       `json:"total"`
+	} `json:"episodes"`
+}
+
+// FetchOptions tunes how GetTracks expands a link into tracks.
+type FetchOptions struct {
+	// AlbumGroups limits artist links to these album groups
+	// (album, single, compilation, appears_on). Empty means DefaultAlbumGroups.
+	AlbumGroups []string
+
+	// Market is an ISO 3166-1 alpha-2 country code. Empty means the
+	// client's default market.
+	Market string
+}
+
 type ImageData struct {
 	Data     []byte
+	FileID   string // Telegram file_id of a cover sent before; Data is empty then
 	Filename string
-	URL      string
+	URL      string // where the cover was downloaded from
 	TrackID  string
+	Position int          // 1-based order of the cover in the source listing
+	Palette  []color.RGBA // main colors, dominant first; filled by imaging.CoverPalette
+
+	// RequestedURL is the URL the cover was asked for. It differs from URL
+	// when the original-size image was missing and FallbackURL was downloaded.
+	RequestedURL string
+	FallbackURL  string
+
+	// Album (or show) the cover belongs to and the artists credited on it
+	Album   SimpleAlbum
+	Artists []Artist
+}
+
+// CacheURL returns the URL a cover is looked up by before downloading it.
+func (img *ImageData) CacheURL() string {
+	if img.RequestedURL != "" {
+		return img.RequestedURL
+	}
+	return img.URL
 }
@@ -1,42 +1,43 @@
 package spotify
 
 import (
-	"fmt"
-	"regexp"
 	"strings"
 )
 
-func CleanURL(rawURL string) string {
-	if idx := strings.Index(rawURL, "?"); idx != -1 {
-		rawURL = rawURL[:idx]
-	}
-	return rawURL
-}
+var (
+	// DefaultAlbumGroups covers an artist's own releases.
+	DefaultAlbumGroups = []string{"album", "single", "compilation"}
 
-func ExtractID(rawURL, typ string) (string, error) {
-	cleanedURL := CleanURL(rawURL)
-	pattern := fmt.Sprintf(`%s/([a-zA-Z0-9]+)`, typ)
-	re := regexp.MustCompile(pattern)
-	matches := re.FindStringSubmatch(cleanedURL)
-	if len(matches) < 2 {
-		return "", fmt.Errorf("cannot extract %s ID", typ)
+	validAlbumGroups = map[string]bool{
+		"album":       true,
+		"single":      true,
+		"compilation": true,
+		"appears_on":  true,
 	}
-	return matches[1], nil
-}
+)
 
-func DetectURLType(rawURL string) string {
-	cleanedURL := CleanURL(rawURL)
-	if strings.Contains(cleanedURL, "/track/") {
-		return "track"
-	} else if strings.Contains(cleanedURL, "/album/") {
-		return "album"
-	} else if strings.Contains(cleanedURL, "/playlist/") {
-		return "playlist"
+// ParseAlbumGroups parses a comma-separated list such as "album,single".
+// Unknown groups are ignored; plural forms ("singles") are accepted.
+func ParseAlbumGroups(value string) []string {
+	var groups []string
+	seen := make(map[string]bool)
+	for _, group := range strings.Split(value, ",") {
+		group = strings.ToLower(strings.TrimSpace(group))
+		group = strings.TrimSuffix(group, "s")
+		if !validAlbumGroups[group] || seen[group] {
+			continue
+		}
+		seen[group] = true
+		groups = append(groups, group)
 	}
-	return "unknown"
+	return groups
 }
 
-func FindSpotifyURL(text string) string {
-	re := regexp.MustCompile(`https?://open\.spotify\.com/(track|album|playlist)/[a-zA-Z0-9]+`)
-	return re.FindString(text)
+// ParseMarket validates an ISO 3166-1 alpha-2 country code such as "JP".
+func ParseMarket(value string) (string, bool) {
+	market := strings.ToUpper(strings.TrimSpace(value))
+	if len(market) != 2 || market[0] < 'A' || market[0] > 'Z' || market[1] < 'A' || market[1] > 'Z' {
+		return "", false
+	}
+	return market, true
 }
@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"sync"
+	"time"
+
+	"image2spotify/internal/spotify"
+
+	"github.com/rs/zerolog/log"
+)
+
+// albumFlushTimeout is how long a partial media group waits for more covers
+const albumFlushTimeout = 5 * time.Second
+
+// albumBatcher buffers covers from the streaming callback into media groups
+// of up to MaxAlbumSize. A partial group is sent when no cover arrives for
+// albumFlushTimeout and when Flush is called at the end of the job.
+type albumBatcher struct {
+	sender *Sender
+	chatID int64
+	onSent func(count int)
+
+	mu      sync.Mutex
+	pending []AlbumItem
+	timer   *time.Timer
+}
+
+func newAlbumBatcher(sender *Sender, chatID int64, onSent func(count int)) *albumBatcher {
+	return &albumBatcher{
+		sender: sender,
+		chatID: chatID,
+		onSent: onSent,
+	}
+}
+
+// Add uploads the cover to the log channel, unless its file_id is cached,
+// and queues it for the next group.
+func (b *albumBatcher) Add(img *spotify.ImageData, index int, caption *Caption) error {
+	if !b.sender.sendable(img) {
+		return nil
+	}
+	fileID := b.sender.coverFileID(img, index, false)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, AlbumItem{Image: img, FileID: fileID, Caption: caption})
+	if len(b.pending) >= b.sender.MaxAlbumSize() {
+		return b.flushLocked()
+	}
+
+	if b.timer == nil {
+		b.timer = time.AfterFunc(albumFlushTimeout, func() {
+			if err := b.Flush(); err != nil {
+				log.Error().Err(err).Int64("chat_id", b.chatID).Msg("Failed to flush album on timeout")
+			}
+		})
+	} else {
+		b.timer.Reset(albumFlushTimeout)
+	}
+	return nil
+}
+
+// Flush sends the covers that are still waiting.
+func (b *albumBatcher) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked()
+}
+
+func (b *albumBatcher) flushLocked() error {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	items := b.pending
+	b.pending = nil
+	if err := b.sender.SendAlbum(b.chatID, items); err != nil {
+		return err
+	}
+	if b.onSent != nil {
+		b.onSent(len(items))
+	}
+	return nil
+}
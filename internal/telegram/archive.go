@@ -0,0 +1,191 @@
+package telegram
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"image2spotify/internal/spotify"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Space kept free in every part for the zip directory and the manifests
+const (
+	archiveReserve         = 16 * 1024
+	archiveReservePerEntry = 1024
+)
+
+// manifestEntry describes one cover in the archive manifest.
+type manifestEntry struct {
+	File       string   `json:"file"`
+	Album      string   `json:"album"`
+	AlbumID    string   `json:"album_id,omitempty"`
+	Artists    []string `json:"artists"`
+	ArtistIDs  []string `json:"artist_ids,omitempty"`
+	Year       string   `json:"year,omitempty"`
+	TrackID    string   `json:"track_id,omitempty"`
+	SpotifyURL string   `json:"spotify_url,omitempty"`
+	ImageURL   string   `json:"image_url"`
+}
+
+// archiveBuilder writes covers into a ZIP file as they arrive. When the next
+// cover would push the file over the size limit, the part is closed with its
+// manifest (manifest.json and manifest.csv) and sent as a document.
+type archiveBuilder struct {
+	sender *Sender
+	chatID int64
+	name   string
+	limit  int64
+	onSent func(count int)
+
+	part    int
+	buf     bytes.Buffer
+	zw      *zip.Writer
+	entries []manifestEntry
+}
+
+func newArchiveBuilder(sender *Sender, chatID int64, onSent func(count int)) *archiveBuilder {
+	return &archiveBuilder{
+		sender: sender,
+		chatID: chatID,
+		name:   "spotify-covers-" + time.Now().Format("2006-01-02-150405"),
+		limit:  int64(sender.maxFileSizeMB) * 1024 * 1024,
+		onSent: onSent,
+	}
+}
+
+// Add writes the cover into the current part.
+func (a *archiveBuilder) Add(img *spotify.ImageData, index int) error {
+	if !a.sender.sendable(img) {
+		return nil
+	}
+
+	if a.zw != nil && a.size()+int64(len(img.Data)) > a.limit {
+		if err := a.sendPart(false); err != nil {
+			return err
+		}
+	}
+	if a.zw == nil {
+		a.part++
+		a.buf.Reset()
+		a.zw = zip.NewWriter(&a.buf)
+		a.entries = nil
+	}
+
+	name := documentName(img)
+	// Обложки уже сжаты, поэтому храним их без сжатия
+	w, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(img.Data); err != nil {
+		return err
+	}
+
+	a.entries = append(a.entries, newManifestEntry(name, img))
+	return nil
+}
+
+// Finish sends the last part.
+func (a *archiveBuilder) Finish() error {
+	if a.zw == nil {
+		return nil
+	}
+	return a.sendPart(true)
+}
+
+// size estimates the finished part size with its directory and manifests.
+func (a *archiveBuilder) size() int64 {
+	return int64(a.buf.Len()) + archiveReserve + int64(len(a.entries)+1)*archiveReservePerEntry
+}
+
+// sendPart closes the current part and sends it. A job that fits in one
+// part gets a file name without the part number.
+func (a *archiveBuilder) sendPart(last bool) error {
+	entries := a.entries
+	zw := a.zw
+	a.zw = nil
+	a.entries = nil
+
+	if err := writeManifests(zw, entries); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	fileName := a.name + ".zip"
+	caption := fmt.Sprintf("📦 %d covers", len(entries))
+	if a.part > 1 || !last {
+		fileName = fmt.Sprintf("%s-part%d.zip", a.name, a.part)
+		caption = fmt.Sprintf("📦 Part %d: %d covers", a.part, len(entries))
+	}
+
+	log.Debug().
+		Int64("chat_id", a.chatID).
+		Int("part", a.part).
+		Int("covers", len(entries)).
+		Int("size", a.buf.Len()).
+		Msg("Sending archive part")
+
+	if err := a.sender.SendFile(a.chatID, a.buf.Bytes(), fileName, caption); err != nil {
+		return err
+	}
+	if a.onSent != nil {
+		a.onSent(len(entries))
+	}
+	return nil
+}
+
+func newManifestEntry(name string, img *spotify.ImageData) manifestEntry {
+	entry := manifestEntry{
+		File:       name,
+		Album:      img.Album.Name,
+		AlbumID:    img.Album.ID,
+		Year:       img.Album.Year(),
+		TrackID:    img.TrackID,
+		SpotifyURL: img.Album.ExternalURLs.Spotify,
+		ImageURL:   img.URL,
+	}
+	for _, artist := range img.Artists {
+		entry.Artists = append(entry.Artists, artist.Name)
+		if artist.ID != "" {
+			entry.ArtistIDs = append(entry.ArtistIDs, artist.ID)
+		}
+	}
+	return entry
+}
+
+// writeManifests adds manifest.json and manifest.csv to the archive.
+func writeManifests(zw *zip.Writer, entries []manifestEntry) error {
+	w, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entries); err != nil {
+		return err
+	}
+
+	w, err = zw.Create("manifest.csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"file", "album", "album_id", "artists", "artist_ids", "year", "track_id", "spotify_url", "image_url"})
+	for _, e := range entries {
+		cw.Write([]string{
+			e.File, e.Album, e.AlbumID,
+			strings.Join(e.Artists, "; "), strings.Join(e.ArtistIDs, "; "),
+			e.Year, e.TrackID, e.SpotifyURL, e.ImageURL,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
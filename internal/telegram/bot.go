@@ -34,8 +34,17 @@ func NewBot(cfg *config.Config, proc *processor.Processor) (*Bot, error) {
 		cfg.MaxFileSizeMB,
 		cfg.MaxMessagesPerSecond,
 		cfg.LogChannelID,
+		newFileIDStore(cfg.FileIDCacheFile),
 	)
-	handlers := NewHandlers(bot, proc, sender)
+	sender.SetDownloader(proc.DownloadImage)
+	settings := newSettingsStore(cfg.UserSettingsFile, UserSettings{
+		Resolution: cfg.DefaultResolution,
+		Caption:    defaultCaptionSetting(cfg.CaptionsEnabled),
+		Delivery:   defaultDelivery(cfg.DefaultDelivery),
+		Filename:   cfg.FilenameTemplate,
+	})
+	captions := newCaptionRenderer(cfg.CaptionTemplate, cfg.CaptionParseMode)
+	handlers := NewHandlers(bot, proc, sender, settings, captions, cfg.AdminUserIDs)
 
 	b := &Bot{
 		bot:       bot,
@@ -52,6 +61,14 @@ func NewBot(cfg *config.Config, proc *processor.Processor) (*Bot, error) {
 func (b *Bot) setupHandlers() {
 	b.bot.Handle("/start", b.handlers.HandleStart)
 	b.bot.Handle("/help", b.handlers.HandleStart)
+	b.bot.Handle("/resolution", b.handlers.HandleResolution)
+	b.bot.Handle("/caption", b.handlers.HandleCaption)
+	b.bot.Handle("/mode", b.handlers.HandleMode)
+	b.bot.Handle("/filename", b.handlers.HandleFilename)
+	b.bot.Handle("/wallpaper", b.handlers.HandleWallpaper)
+	b.bot.Handle("/stickers", b.handlers.HandleStickers)
+	b.bot.Handle("/tracklist", b.handlers.HandleTracklist)
+	b.bot.Handle("/reindex", b.handlers.HandleReindex)
 	b.bot.Handle(tele.OnText, b.handlers.HandleMessage)
 	b.bot.Handle(tele.OnQuery, b.handlers.HandleInlineQuery)
 }
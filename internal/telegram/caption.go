@@ -0,0 +1,139 @@
+package telegram
+
+import (
+	"html"
+	"strings"
+
+	"image2spotify/internal/imaging"
+	"image2spotify/internal/spotify"
+	"image2spotify/internal/tmpl"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// maxCaptionLength is Telegram's limit for media captions
+const maxCaptionLength = 1024
+
+// Built-in caption templates, one per parse mode, used when captions are
+// turned on without a custom template.
+const (
+	DefaultCaptionTemplate           = "<b>{album}</b> ({year})\n{artists}\n<a href=\"{link}\">Open in Spotify</a>"
+	DefaultCaptionTemplateMarkdownV2 = "*{album}* \\({year}\\)\n{artists}\n[Open in Spotify]({link})"
+	DefaultCaptionTemplatePlain      = "{album} ({year})\n{artists}\n{link}"
+)
+
+// Caption settings values besides a custom template
+const (
+	captionOn  = "on"
+	captionOff = "off"
+)
+
+// Caption is the text sent under a cover, written in ParseMode markup.
+type Caption struct {
+	Text      string
+	ParseMode tele.ParseMode
+}
+
+var markdownV2Escaper = strings.NewReplacer(
+	"\\", "\\\\", "_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)",
+	"~", "\\~", "`", "\\`", ">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=",
+	"|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!",
+)
+
+// captionRenderer fills caption templates with cover metadata.
+type captionRenderer struct {
+	template  string // used for the "on" setting
+	parseMode tele.ParseMode
+}
+
+func newCaptionRenderer(template, parseMode string) *captionRenderer {
+	mode := parseCaptionMode(parseMode)
+	if template == "" {
+		template = defaultCaptionTemplate(mode)
+	}
+	return &captionRenderer{template: template, parseMode: mode}
+}
+
+// defaultCaptionTemplate returns the built-in template written in the markup
+// of the parse mode.
+func defaultCaptionTemplate(mode tele.ParseMode) string {
+	switch mode {
+	case tele.ModeMarkdownV2:
+		return DefaultCaptionTemplateMarkdownV2
+	case tele.ModeDefault:
+		return DefaultCaptionTemplatePlain
+	}
+	return DefaultCaptionTemplate
+}
+
+func defaultCaptionSetting(enabled bool) string {
+	if enabled {
+		return captionOn
+	}
+	return captionOff
+}
+
+// templateFor returns the template for a caption setting, "" when captions are off.
+func (r *captionRenderer) templateFor(setting string) string {
+	switch setting {
+	case "", captionOff:
+		return ""
+	case captionOn:
+		return r.template
+	}
+	return setting
+}
+
+// UsesPalette reports whether captions need the cover colors, and so the
+// cover bytes.
+func (r *captionRenderer) UsesPalette(setting string) bool {
+	template := r.templateFor(setting)
+	return strings.Contains(template, "{color") || strings.Contains(template, "{palette")
+}
+
+// parseCaptionMode accepts "HTML", "MarkdownV2" or "none".
+func parseCaptionMode(value string) tele.ParseMode {
+	switch strings.ToLower(value) {
+	case "markdown", "markdownv2":
+		return tele.ModeMarkdownV2
+	case "none", "text", "plain":
+		return tele.ModeDefault
+	default:
+		return tele.ModeHTML
+	}
+}
+
+// Render returns the caption for a cover, or nil when captions are off.
+// setting is the user's caption setting: "on", "off" or a custom template.
+func (r *captionRenderer) Render(setting string, img *spotify.ImageData, index, total int) *Caption {
+	template := r.templateFor(setting)
+	if template == "" {
+		return nil
+	}
+
+	// The palette is only computed when the template uses it
+	if r.UsesPalette(setting) {
+		imaging.CoverPalette(img)
+	}
+
+	var escape func(string) string
+	switch r.parseMode {
+	case tele.ModeHTML:
+		escape = html.EscapeString
+	case tele.ModeMarkdownV2:
+		escape = markdownV2Escaper.Replace
+	}
+
+	text := tmpl.ExpandLimited(template, tmpl.CoverValues(img, index, total), escape, maxCaptionLength)
+	if text == "" {
+		return nil
+	}
+	return &Caption{Text: text, ParseMode: r.parseMode}
+}
+
+// captionRejected reports a Telegram error about the caption itself: broken
+// markup or a caption over the length limit.
+func captionRejected(err error) bool {
+	text := err.Error()
+	return strings.Contains(text, "can't parse entities") || strings.Contains(text, "caption is too long")
+}
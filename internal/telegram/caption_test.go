@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"testing"
+
+	"image2spotify/internal/spotify"
+)
+
+func TestDefaultCaptionPerParseMode(t *testing.T) {
+	img := &spotify.ImageData{
+		Album: spotify.SimpleAlbum{
+			ID:          "4aawyAB9vmqN3uQ7FjRGTy",
+			Name:        "Rock & Roll (Live)",
+			ReleaseDate: "2009-10-05",
+		},
+		Artists: []spotify.Artist{{Name: "AC/DC"}, {Name: "Guest_Star"}},
+	}
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{
+			mode: "HTML",
+			want: "<b>Rock &amp; Roll (Live)</b> (2009)\nAC/DC, Guest_Star\n<a href=\"https://open.spotify.com/album/4aawyAB9vmqN3uQ7FjRGTy\">Open in Spotify</a>",
+		},
+		{
+			mode: "MarkdownV2",
+			want: "*Rock & Roll \\(Live\\)* \\(2009\\)\nAC/DC, Guest\\_Star\n[Open in Spotify](https://open\\.spotify\\.com/album/4aawyAB9vmqN3uQ7FjRGTy)",
+		},
+		{
+			mode: "none",
+			want: "Rock & Roll (Live) (2009)\nAC/DC, Guest_Star\nhttps://open.spotify.com/album/4aawyAB9vmqN3uQ7FjRGTy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			caption := newCaptionRenderer("", tt.mode).Render(captionOn, img, 1, 1)
+			if caption == nil {
+				t.Fatal("Render returned no caption")
+			}
+			if caption.Text != tt.want {
+				t.Errorf("caption =\n%s\nwant\n%s", caption.Text, tt.want)
+			}
+		})
+	}
+}
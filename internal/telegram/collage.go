@@ -0,0 +1,206 @@
+package telegram
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+
+	"image2spotify/internal/imaging"
+	"image2spotify/internal/spotify"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Collage limits: covers kept for one job and covers on a single poster
+const (
+	maxCollageCovers    = 500
+	maxPosterCovers     = 400
+	defaultCollageWidth = 2048
+	minCollageWidth     = 256
+	maxCollageWidth     = 4096
+)
+
+// Collage orders
+const (
+	orderPlaylist = "playlist"
+	orderColor    = "color"
+	orderYear     = "year"
+)
+
+// collageSettings are the options of one collage request.
+type collageSettings struct {
+	Cols, Rows int // zero for one auto-sized poster
+	Order      string
+	Width      int
+}
+
+// parseCollageSettings reads "grid=4x4", "order=color" and "size=2048".
+func parseCollageSettings(opts map[string]string) (collageSettings, error) {
+	settings := collageSettings{Order: orderPlaylist, Width: defaultCollageWidth}
+
+	if grid, ok := opts["grid"]; ok && grid != "poster" && grid != "auto" {
+		colsText, rowsText, found := strings.Cut(strings.ToLower(grid), "x")
+		cols, colsErr := strconv.Atoi(colsText)
+		rows, rowsErr := strconv.Atoi(rowsText)
+		if !found || colsErr != nil || rowsErr != nil || cols < 1 || rows < 1 || cols > 20 || rows > 20 {
+			return settings, fmt.Errorf("unknown grid %q, use grid=3x3, grid=4x4, grid=10x10 or grid=poster", grid)
+		}
+		settings.Cols, settings.Rows = cols, rows
+	}
+
+	if value, ok := opts["order"]; ok {
+		order, err := parseCoverOrder(value)
+		if err != nil {
+			return settings, err
+		}
+		settings.Order = order
+	}
+
+	if size, ok := opts["size"]; ok {
+		width, err := strconv.Atoi(strings.TrimSuffix(size, "px"))
+		if err != nil || width < minCollageWidth || width > maxCollageWidth {
+			return settings, fmt.Errorf("unknown size %q, use a width from %d to %d pixels", size, minCollageWidth, maxCollageWidth)
+		}
+		settings.Width = width
+	}
+
+	return settings, nil
+}
+
+// parseCoverOrder reads the order of collage and slideshow covers.
+func parseCoverOrder(value string) (string, error) {
+	switch order := strings.ToLower(value); order {
+	case orderPlaylist, orderColor, orderYear:
+		return order, nil
+	case "colour":
+		return orderColor, nil
+	}
+	return "", fmt.Errorf("unknown order %q, use order=playlist, order=color or order=year", value)
+}
+
+// collageBuilder keeps the downloaded covers of a job and renders them into
+// grids or a poster at the end.
+type collageBuilder struct {
+	sender   *Sender
+	chatID   int64
+	settings collageSettings
+	onSent   func(count int)
+
+	covers  []*spotify.ImageData
+	dropped int
+}
+
+func newCollageBuilder(sender *Sender, chatID int64, settings collageSettings, onSent func(count int)) *collageBuilder {
+	return &collageBuilder{
+		sender:   sender,
+		chatID:   chatID,
+		settings: settings,
+		onSent:   onSent,
+	}
+}
+
+// Add keeps the cover for the collage.
+func (b *collageBuilder) Add(img *spotify.ImageData) error {
+	if len(img.Data) == 0 {
+		return nil
+	}
+	if len(b.covers) >= maxCollageCovers {
+		b.dropped++
+		return nil
+	}
+	b.covers = append(b.covers, img)
+	return nil
+}
+
+// Finish renders the collages and sends them.
+func (b *collageBuilder) Finish() error {
+	if len(b.covers) == 0 {
+		return nil
+	}
+	if b.dropped > 0 {
+		log.Warn().Int("dropped", b.dropped).Int("kept", len(b.covers)).Msg("Too many covers for a collage")
+	}
+
+	covers := orderCovers(b.covers, b.settings.Order)
+	cols, rows := b.settings.Cols, b.settings.Rows
+	if cols == 0 {
+		if len(covers) > maxPosterCovers {
+			covers = covers[:maxPosterCovers]
+		}
+		cols, rows = imaging.GridFor(len(covers))
+	}
+
+	perPage := cols * rows
+	pages := (len(covers) + perPage - 1) / perPage
+	for page := 0; page < pages; page++ {
+		pageCovers := covers[page*perPage : min((page+1)*perPage, len(covers))]
+
+		tiles := make([]*image.RGBA, 0, len(pageCovers))
+		for _, img := range pageCovers {
+			tile, err := imaging.Decode(img.Data)
+			if err != nil {
+				log.Debug().Err(err).Str("track_id", img.TrackID).Msg("Failed to decode cover for collage")
+				continue
+			}
+			tiles = append(tiles, tile)
+		}
+
+		data, err := imaging.EncodeJPEG(imaging.Collage(tiles, cols, rows, b.settings.Width), 90)
+		if err != nil {
+			return err
+		}
+
+		caption := fmt.Sprintf("🖼 %dx%d, %d covers", cols, rows, len(tiles))
+		if pages > 1 {
+			caption = fmt.Sprintf("🖼 %d/%d · %dx%d, %d covers", page+1, pages, cols, rows, len(tiles))
+		}
+		// Covers over the collage or poster limit are left out, say so on the last page
+		if total := len(b.covers) + b.dropped; len(covers) < total && page == pages-1 {
+			caption += fmt.Sprintf("\n%d of %d covers shown", len(covers), total)
+		}
+		if err := b.sender.SendPicture(b.chatID, data, caption); err != nil {
+			return err
+		}
+		if b.onSent != nil {
+			b.onSent(len(tiles))
+		}
+	}
+
+	return nil
+}
+
+// orderCovers returns the covers in the requested order. Covers arrive in
+// download order, so even playlist order needs sorting.
+func orderCovers(kept []*spotify.ImageData, order string) []*spotify.ImageData {
+	covers := append([]*spotify.ImageData(nil), kept...)
+	sort.SliceStable(covers, func(i, j int) bool {
+		return covers[i].Position < covers[j].Position
+	})
+
+	switch order {
+	case orderYear:
+		sort.SliceStable(covers, func(i, j int) bool {
+			return covers[i].Album.ReleaseDate < covers[j].Album.ReleaseDate
+		})
+	case orderColor:
+		keys := make(map[*spotify.ImageData]color.RGBA, len(covers))
+		for _, img := range covers {
+			keys[img] = coverColor(img)
+		}
+		sort.SliceStable(covers, func(i, j int) bool {
+			return imaging.ColorLess(keys[covers[i]], keys[covers[j]])
+		})
+	}
+	return covers
+}
+
+// coverColor returns the dominant color of a cover.
+func coverColor(img *spotify.ImageData) color.RGBA {
+	if palette := imaging.CoverPalette(img); len(palette) > 0 {
+		return palette[0]
+	}
+	return color.RGBA{}
+}
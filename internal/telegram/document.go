@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"bytes"
+	"net/http"
+
+	"image2spotify/internal/spotify"
+	"image2spotify/internal/tmpl"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// coverMedia builds the message for a cover: a photo, or a document that
+// keeps the original bytes. With fileID the cover is resent without upload.
+func coverMedia(img *spotify.ImageData, fileID string, document bool, caption string) tele.Sendable {
+	file := tele.File{FileID: fileID}
+	if fileID == "" {
+		file = tele.FromReader(bytes.NewReader(img.Data))
+	}
+
+	if document {
+		return &tele.Document{
+			File:     file,
+			FileName: documentName(img),
+			MIME:     http.DetectContentType(img.Data),
+			Caption:  caption,
+		}
+	}
+	return &tele.Photo{File: file, Caption: caption}
+}
+
+// mediaFileID returns the file_id of the photo or document in a sent message.
+func mediaFileID(msg *tele.Message) string {
+	switch {
+	case msg == nil:
+		return ""
+	case msg.Photo != nil:
+		return msg.Photo.FileID
+	case msg.Document != nil:
+		return msg.Document.FileID
+	}
+	return ""
+}
+
+// documentName returns the file name a cover is sent under: img.Filename when
+// it was set by a tmpl.Namer, otherwise the default template.
+func documentName(img *spotify.ImageData) string {
+	if img.Filename != "" {
+		return img.Filename
+	}
+	return tmpl.NewNamer("").Name(img, 0, 0)
+}
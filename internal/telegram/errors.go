@@ -0,0 +1,40 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"image2spotify/internal/spotify"
+)
+
+// userErrorMessage turns a processing error into a message for the user.
+func userErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, spotify.ErrEditorialPlaylist):
+		return "This is a Spotify editorial playlist. Spotify does not share those through its API."
+	case errors.Is(err, spotify.ErrPrivatePlaylist):
+		return "This playlist is private or does not exist. Make it public and try again."
+	}
+
+	if apiErr, ok := spotify.AsAPIError(err); ok {
+		switch {
+		case apiErr.NotFound():
+			return "Nothing found at this link. Please check it and try again."
+		case apiErr.Status == http.StatusBadRequest:
+			return "Spotify did not accept this link. Please check it and try again."
+		case apiErr.RateLimited():
+			return "Spotify is rate limiting requests right now. Please try again in a few minutes."
+		case apiErr.ServerError():
+			return "Spotify is having problems right now. Please try again later."
+		}
+	}
+
+	return fmt.Sprintf("Error: %v", err)
+}
+
+// partialReadNote tells the user that a listing was only read in part.
+func partialReadNote(partial *spotify.PartialError) string {
+	return fmt.Sprintf("⚠️ The %s was only partially read: %d of %d items (%s)",
+		partial.Kind, partial.Fetched, partial.Total, userErrorMessage(partial.Err))
+}
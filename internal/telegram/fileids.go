@@ -0,0 +1,230 @@
+package telegram
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"image2spotify/internal/spotify"
+
+	"github.com/rs/zerolog/log"
+)
+
+// fileIDEntry is one line of the file_id cache file. A rejected entry
+// removes every key that points to its file_id.
+type fileIDEntry struct {
+	URL      string `json:"url,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+	FileID   string `json:"file_id"`
+	Rejected bool   `json:"rejected,omitempty"`
+}
+
+// fileIDStore remembers the file_id of covers already sent as photos, by
+// image URL and by content hash. Covers known by URL are sent without being
+// downloaded, covers known by hash without the log channel upload.
+//
+// Changes are appended to a JSON lines file, which is compacted on start.
+// A nil store caches nothing, so callers don't check whether it's enabled.
+type fileIDStore struct {
+	mu     sync.RWMutex
+	path   string
+	file   *os.File
+	byURL  map[string]string
+	byHash map[string]string
+}
+
+// newFileIDStore loads the cache file. An empty path disables the cache.
+func newFileIDStore(path string) *fileIDStore {
+	if path == "" {
+		return nil
+	}
+	s := &fileIDStore{
+		path:   path,
+		byURL:  make(map[string]string),
+		byHash: make(map[string]string),
+	}
+
+	if err := s.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to read file_id cache")
+	}
+	if err := s.compact(); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to compact file_id cache")
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to open file_id cache, new entries will not be saved")
+	}
+	s.file = file
+
+	log.Info().Int("urls", len(s.byURL)).Int("hashes", len(s.byHash)).Msg("File_id cache loaded")
+	return s
+}
+
+func (s *fileIDStore) load() error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry fileIDEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Обрезанная последняя строка после падения не должна ломать весь кэш
+			continue
+		}
+		s.apply(entry)
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the file with the current entries only.
+func (s *fileIDStore) compact() error {
+	tmp := s.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for url, fileID := range s.byURL {
+		encoder.Encode(fileIDEntry{URL: url, FileID: fileID})
+	}
+	for hash, fileID := range s.byHash {
+		encoder.Encode(fileIDEntry{Hash: hash, FileID: fileID})
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *fileIDStore) apply(entry fileIDEntry) {
+	if entry.Rejected {
+		for url, fileID := range s.byURL {
+			if fileID == entry.FileID {
+				delete(s.byURL, url)
+			}
+		}
+		for hash, fileID := range s.byHash {
+			if fileID == entry.FileID {
+				delete(s.byHash, hash)
+			}
+		}
+		return
+	}
+	if entry.FileID == "" {
+		return
+	}
+	if entry.URL != "" {
+		s.byURL[entry.URL] = entry.FileID
+	}
+	if entry.Hash != "" {
+		s.byHash[entry.Hash] = entry.FileID
+	}
+}
+
+// FileID returns the file_id of the cover at imageURL, or "" when it's unknown.
+func (s *fileIDStore) FileID(imageURL string) string {
+	if s == nil {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byURL[imageURL]
+}
+
+// ByHash returns the file_id of a cover with the same bytes, or "" when it's unknown.
+func (s *fileIDStore) ByHash(data []byte) string {
+	if s == nil || len(data) == 0 {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byHash[imageHash(data)]
+}
+
+// Put remembers the file_id of a cover sent as a photo, under the URL the
+// cover is looked up by. Pictures rendered by the bot have no URL and are
+// not cached.
+func (s *fileIDStore) Put(img *spotify.ImageData, fileID string) {
+	if s == nil || fileID == "" || img.CacheURL() == "" {
+		return
+	}
+	entry := fileIDEntry{URL: img.CacheURL(), FileID: fileID}
+	if len(img.Data) > 0 {
+		entry.Hash = imageHash(img.Data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byURL[entry.URL] == fileID && (entry.Hash == "" || s.byHash[entry.Hash] == fileID) {
+		return
+	}
+	s.apply(entry)
+	s.append(entry)
+}
+
+// Invalidate forgets a file_id that Telegram rejected.
+func (s *fileIDStore) Invalidate(fileID string) {
+	if s == nil || fileID == "" {
+		return
+	}
+	entry := fileIDEntry{FileID: fileID, Rejected: true}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apply(entry)
+	s.append(entry)
+	log.Info().Str("file_id", fileID).Msg("Rejected file_id removed from cache")
+}
+
+// append writes an entry to the file; callers hold s.mu.
+func (s *fileIDStore) append(entry fileIDEntry) {
+	if s.file == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		log.Warn().Err(err).Str("path", s.path).Msg("Failed to save file_id cache entry")
+	}
+}
+
+// Close closes the cache file.
+func (s *fileIDStore) Close() error {
+	if s == nil || s.file == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+func imageHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileIDRejected reports a Telegram error about an unknown or expired file_id.
+func fileIDRejected(err error) bool {
+	text := strings.ToLower(err.Error())
+	return strings.Contains(text, "wrong file identifier") ||
+		strings.Contains(text, "wrong remote file identifier") ||
+		strings.Contains(text, "file_id_invalid") ||
+		strings.Contains(text, "file reference")
+}
@@ -0,0 +1,34 @@
+package telegram
+
+import (
+	"path/filepath"
+	"testing"
+
+	"image2spotify/internal/spotify"
+)
+
+func TestFileIDStoreFallbackCover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file_ids.jsonl")
+	store := newFileIDStore(path)
+
+	img := &spotify.ImageData{
+		URL:          "https://i.scdn.co/image/ab67616d0000b2731234",
+		RequestedURL: "https://i.scdn.co/image/ab67616d000082c11234",
+		Data:         []byte("cover"),
+	}
+	store.Put(img, "file-1")
+
+	if got := store.FileID(img.RequestedURL); got != "file-1" {
+		t.Errorf("FileID(requested URL) = %q, want file-1", got)
+	}
+	if got := store.ByHash(img.Data); got != "file-1" {
+		t.Errorf("ByHash = %q, want file-1", got)
+	}
+	if got := coverTagURL(coverTag(img)); got != img.RequestedURL {
+		t.Errorf("cover tag URL = %q, want the requested URL", got)
+	}
+
+	if got := newFileIDStore(path).FileID(img.RequestedURL); got != "file-1" {
+		t.Errorf("after reload: FileID(requested URL) = %q, want file-1", got)
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"image2spotify/internal/processor"
 	"image2spotify/internal/spotify"
+	"image2spotify/internal/tmpl"
 
 	"github.com/rs/zerolog/log"
 	tele "gopkg.in/telebot.v4"
@@ -55,14 +57,24 @@ func (ic *inlineCache) Set(key string, images []*spotify.ImageData) {
 type Handlers struct {
 	processor *processor.Processor
 	sender    *Sender
+	settings  *settingsStore
+	captions  *captionRenderer
+	admins    map[int64]bool
 	bot       *tele.Bot
 }
 
-func NewHandlers(bot *tele.Bot, proc *processor.Processor, sender *Sender) *Handlers {
+func NewHandlers(bot *tele.Bot, proc *processor.Processor, sender *Sender, settings *settingsStore, captions *captionRenderer, adminIDs []int64) *Handlers {
+	admins := make(map[int64]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
 	return &Handlers{
 		bot:       bot,
 		processor: proc,
 		sender:    sender,
+		settings:  settings,
+		captions:  captions,
+		admins:    admins,
 	}
 }
 
@@ -75,9 +87,46 @@ Send me any Spotify link and I'll download the covers for you\!
 • Track: ` + "`https://open.spotify.com/track/...`" + `
 • Album: ` + "`https://open.spotify.com/album/...`" + `
 • Playlist: ` + "`https://open.spotify.com/playlist/...`" + `
+• Artist: ` + "`https://open.spotify.com/artist/...`" + `
+• Podcast: ` + "`https://open.spotify.com/show/...`" + `
+• Episode: ` + "`https://open.spotify.com/episode/...`" + `
+
+Add ` + "`market=JP`" + ` after a link to check a specific country\.
+Send several links in one message to get all their covers in one go\.
+Spotify URIs \(` + "`spotify:album:...`" + `\), embed links and ` + "`spotify.link`" + ` short links work too\.
+
+*Artist discography:*
+Albums, singles and compilations are included by default\. Pick groups with ` + "`groups=album,single,compilation,appears_on`" + ` after the link\.
+
+*Cover size:*
+Add ` + "`res=original`" + ` after a link for full\-size art, or ` + "`res=standard`" + ` / ` + "`res=thumbnail`" + ` for smaller files\. Set your default with ` + "`/resolution original`" + `\.
+
+*Delivery:*
+Get covers grouped into albums of up to 10 with ` + "`/mode album`" + `, or add ` + "`as=album`" + ` after a link\. ` + "`/mode archive`" + ` packs them into ZIP files with a manifest, ` + "`/mode document`" + ` sends uncompressed files with the original quality, ` + "`/mode photo`" + ` sends every cover as a photo\.
+
+*Collages:*
+Add ` + "`as=collage`" + ` after a playlist link to get one poster of all its covers\. Pick a grid with ` + "`grid=3x3`" + `, ` + "`grid=4x4`" + ` or ` + "`grid=10x10`" + `, the order with ` + "`order=color`" + ` or ` + "`order=year`" + `, and the width with ` + "`size=2048`" + `\.
+
+*Slideshows:*
+Add ` + "`as=gif`" + ` after a playlist link to get its covers as an animated slideshow\. Set how long each cover is shown with ` + "`frame=0.5s`" + `, or the whole length with ` + "`length=10s`" + `, and the size with ` + "`size=480`" + `\.
+
+*Palettes:*
+Add ` + "`as=palette`" + ` after a link to get every cover with its dominant color and a 5\-color palette\. ` + "`{color}`" + ` and ` + "`{palette}`" + ` work in caption templates too\.
+
+*Wallpapers:*
+Send ` + "`/wallpaper`" + ` with a track or album link to get the cover as a 1080x1920 phone wallpaper\. Add ` + "`desktop`" + ` for 2560x1440 and ` + "`palette`" + ` for a gradient backdrop instead of the blurred cover\.
+
+*Tracklists:*
+Send ` + "`/tracklist`" + ` with an album or playlist link to get a poster with the cover and every track with its length, ready to share\.
+
+*Stickers:*
+Send ` + "`/stickers`" + ` with a playlist, album or artist link to get a sticker pack of its covers, up to 120 stickers\.
+
+*Captions:*
+Turn on album, artists, year and a link under every cover with ` + "`/caption on`" + `, or add ` + "`caption=on`" + ` after a link\. ` + "`/caption`" + ` followed by text sets your own template with ` + "`{album}`" + `, ` + "`{artists}`" + `, ` + "`{year}`" + ` and ` + "`{link}`" + `\.
 
 *Features:*
-✅ High\-quality images \(640x640\)
+✅ High\-quality images, up to full\-size originals
 ✅ Full playlist support \(no limits\)
 ✅ Inline mode support \(max 50 results\)
 ✅ Fast parallel processing
@@ -91,19 +140,77 @@ Just send me a link and I'll do the rest\! 🚀`
 }
 
 func (h *Handlers) HandleMessage(c tele.Context) error {
+	return h.handleRequest(c, "")
+}
+
+// HandleStickers turns the covers of a link into a sticker pack owned by the user.
+func (h *Handlers) HandleStickers(c tele.Context) error {
+	if len(spotify.FindLinks(c.Message().Payload)) == 0 {
+		return c.Send(fmt.Sprintf("Send /stickers followed by a playlist, album or artist link, e.g.\n"+
+			"/stickers https://open.spotify.com/playlist/...\n\n"+
+			"Every cover becomes a sticker in a new pack, up to %d stickers.", maxStickers))
+	}
+	return h.handleRequest(c, deliverySticker)
+}
+
+// handleRequest downloads the covers of the links in the message and delivers
+// them in the given mode, or in the one chosen by the user when it's empty.
+func (h *Handlers) handleRequest(c tele.Context, forcedDelivery string) error {
 	text := c.Text()
 	if text == "" {
 		return c.Send("Please send me a Spotify link.")
 	}
 
-	spotifyURL := spotify.FindSpotifyURL(text)
-	if spotifyURL == "" {
-		return c.Send("No Spotify link found in your message. Please send a valid Spotify track, album, or playlist link.")
+	rawLinks := spotify.FindLinks(text)
+	for _, entity := range c.Message().Entities {
+		if entity.Type == tele.EntityTextLink {
+			rawLinks = append(rawLinks, spotify.FindLinks(entity.URL)...)
+		}
+	}
+	if len(rawLinks) == 0 {
+		return c.Send("No Spotify link found in your message. Please send a valid Spotify track, album, playlist, artist, or podcast link.")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+
+	links, skipped := h.parseLinks(ctx, rawLinks)
+	if len(links) == 0 {
+		return c.Send("Unsupported Spotify link. Please send a track, album, playlist, artist, or podcast link.")
 	}
 
-	urlType := spotify.DetectURLType(spotifyURL)
-	if urlType == "unknown" {
-		return c.Send("Unsupported Spotify link. Please send a track, album, or playlist link.")
+	requestOpts := parseRequestOptions(text)
+	settings := h.settings.Get(c.Sender().ID)
+	opts, err := processOptions(requestOpts, settings)
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+	captionSetting, err := requestCaption(requestOpts, settings)
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+	delivery, err := requestDelivery(requestOpts, settings)
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+	if forcedDelivery != "" {
+		delivery = forcedDelivery
+	}
+	// Фото можно переслать по сохранённому file_id, не скачивая обложку
+	if (delivery == deliveryPhoto || delivery == deliveryAlbum) && !h.captions.UsesPalette(captionSetting) {
+		opts.FileIDs = h.sender.FileIDs()
+	}
+	var collageOpts collageSettings
+	if delivery == deliveryCollage {
+		if collageOpts, err = parseCollageSettings(requestOpts); err != nil {
+			return c.Send("❌ " + err.Error())
+		}
+	}
+	var slideshowOpts slideshowSettings
+	if delivery == deliverySlideshow {
+		if slideshowOpts, err = parseSlideshowSettings(requestOpts); err != nil {
+			return c.Send("❌ " + err.Error())
+		}
 	}
 
 	username := c.Sender().Username
@@ -114,38 +221,34 @@ func (h *Handlers) HandleMessage(c tele.Context) error {
 	log.Info().
 		Int64("user_id", c.Sender().ID).
 		Str("username", username).
-		Str("url", spotifyURL).
-		Str("type", urlType).
+		Int("link_count", len(links)).
+		Int("skipped_links", len(skipped)).
+		Str("url", links[0].Raw).
+		Str("type", string(links[0].Kind)).
+		Str("resolution", string(opts.Resolution)).
+		Str("delivery", delivery).
 		Msg("Processing user request")
 
-	processingMsg, err := c.Bot().Send(c.Sender(), fmt.Sprintf("⏳ Processing %s...", urlType))
+	processingText := fmt.Sprintf("⏳ Processing %s...", links[0].Kind)
+	if len(links) > 1 {
+		processingText = fmt.Sprintf("⏳ Processing %d links...", len(links))
+	}
+	processingMsg, err := c.Bot().Send(c.Sender(), processingText)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to send processing message")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
-	defer cancel()
-
-	// Получаем треки для автоплейлиста (если включено)
-	var trackURIs []string
-	if h.processor.IsAutoPlaylistEnabled() {
-		tracks, _, _, err := h.processor.GetSpotifyClient().GetTracks(ctx, spotifyURL)
-		if err == nil {
-			for _, track := range tracks {
-				if track.ID != "" {
-					trackURIs = append(trackURIs, fmt.Sprintf("spotify:track:%s", track.ID))
-				}
-			}
-		}
-	}
-
 	lastUpdate := time.Now()
 	var sentCount int32
+	sentLabel := "sent"
+	if delivery == deliverySticker {
+		sentLabel = "added to the sticker pack"
+	}
 
 	progressCallback := func(current, total int) {
 		if time.Since(lastUpdate) >= 3*time.Second {
-			updateText := fmt.Sprintf("⏳ Processing: %d/%d downloaded, %d sent",
-				current, total, atomic.LoadInt32(&sentCount))
+			updateText := fmt.Sprintf("⏳ Processing: %d/%d downloaded, %d %s",
+				current, total, atomic.LoadInt32(&sentCount), sentLabel)
 			if processingMsg != nil {
 				c.Bot().Edit(processingMsg, updateText)
 			}
@@ -153,18 +256,98 @@ func (h *Handlers) HandleMessage(c tele.Context) error {
 		}
 	}
 
+	namer := tmpl.NewNamer(settings.Filename)
+	onSent := func(count int) {
+		atomic.AddInt32(&sentCount, int32(count))
+	}
+	var albums *albumBatcher
+	var archive *archiveBuilder
+	var collage *collageBuilder
+	var slideshow *slideshowBuilder
+	var stickers *stickerPackBuilder
+	switch delivery {
+	case deliveryAlbum:
+		albums = newAlbumBatcher(h.sender, c.Chat().ID, onSent)
+	case deliveryArchive:
+		archive = newArchiveBuilder(h.sender, c.Chat().ID, onSent)
+	case deliveryCollage:
+		collage = newCollageBuilder(h.sender, c.Chat().ID, collageOpts, onSent)
+	case deliverySlideshow:
+		slideshow = newSlideshowBuilder(h.sender, c.Chat().ID, slideshowOpts, onSent)
+	case deliverySticker:
+		stickers = newStickerPackBuilder(h.sender, c.Sender().ID, links[0].Kind, onSent)
+		opts.Accept = stickers.Accept
+	}
+
 	imageCallback := func(img *spotify.ImageData, index, total int) error {
-		err := h.sender.StreamImage(c.Chat().ID, username, img, index, total)
+		img.Filename = namer.Name(img, index, total)
+		if archive != nil {
+			return archive.Add(img, index)
+		}
+		if collage != nil {
+			return collage.Add(img)
+		}
+		if slideshow != nil {
+			return slideshow.Add(img)
+		}
+		if stickers != nil {
+			return stickers.Add(img)
+		}
+		if delivery == deliveryPalette {
+			err := h.sender.SendPalette(c.Chat().ID, img)
+			if err == nil {
+				atomic.AddInt32(&sentCount, 1)
+			}
+			return err
+		}
+		caption := h.captions.Render(captionSetting, img, index, total)
+		if albums != nil {
+			return albums.Add(img, index, caption)
+		}
+
+		var err error
+		if delivery == deliveryDocument {
+			err = h.sender.StreamDocument(c.Chat().ID, username, img, index, total, caption)
+		} else {
+			err = h.sender.StreamImage(c.Chat().ID, username, img, index, total, caption)
+		}
 		if err == nil {
 			atomic.AddInt32(&sentCount, 1)
 		}
 		return err
 	}
 
-	err = h.processor.StreamProcessURL(ctx, spotifyURL, imageCallback, progressCallback)
+	summary, err := h.processor.StreamProcessURL(ctx, links, opts, imageCallback, progressCallback)
+	if albums != nil {
+		// Досылаем неполную группу, в том числе если обработка прервалась
+		if flushErr := albums.Flush(); flushErr != nil {
+			log.Error().Err(flushErr).Int64("chat_id", c.Chat().ID).Msg("Failed to send last album")
+		}
+	}
+	if archive != nil {
+		if finishErr := archive.Finish(); finishErr != nil {
+			log.Error().Err(finishErr).Int64("chat_id", c.Chat().ID).Msg("Failed to send last archive part")
+		}
+	}
+	if collage != nil {
+		if processingMsg != nil {
+			c.Bot().Edit(processingMsg, "🎨 Rendering collage...")
+		}
+		if finishErr := collage.Finish(); finishErr != nil {
+			log.Error().Err(finishErr).Int64("chat_id", c.Chat().ID).Msg("Failed to render collage")
+		}
+	}
+	if slideshow != nil {
+		if processingMsg != nil {
+			c.Bot().Edit(processingMsg, "🎞 Rendering slideshow...")
+		}
+		if finishErr := slideshow.Finish(); finishErr != nil {
+			log.Error().Err(finishErr).Int64("chat_id", c.Chat().ID).Msg("Failed to render slideshow")
+		}
+	}
 	if err != nil {
-		log.Error().Err(err).Str("url", spotifyURL).Msg("Failed to process URL")
-		errorMsg := fmt.Sprintf("❌ Error: %v", err)
+		log.Error().Err(err).Str("url", links[0].Raw).Int("link_count", len(links)).Msg("Failed to process URL")
+		errorMsg := "❌ " + userErrorMessage(err)
 		if processingMsg != nil {
 			c.Bot().Edit(processingMsg, errorMsg)
 		} else {
@@ -174,7 +357,8 @@ func (h *Handlers) HandleMessage(c tele.Context) error {
 	}
 
 	// Добавляем треки в автоплейлист (асинхронно)
-	if len(trackURIs) > 0 {
+	trackURIs := summary.TrackURIs
+	if h.processor.IsAutoPlaylistEnabled() && len(trackURIs) > 0 {
 		go func() {
 			if err := h.processor.AddToAutoPlaylist(context.Background(), trackURIs); err != nil {
 				log.Error().Err(err).Int("track_count", len(trackURIs)).Msg("Failed to add to auto-playlist")
@@ -193,14 +377,169 @@ func (h *Handlers) HandleMessage(c tele.Context) error {
 	log.Info().
 		Int64("user_id", c.Sender().ID).
 		Int("image_count", finalCount).
+		Int("link_count", summary.Links).
+		Int("cached", summary.Cached).
 		Int("tracks_added_to_playlist", len(trackURIs)).
 		Msg("Successfully processed request")
 
-	h.sender.SendFinalMessage(c.Chat().ID, username, finalCount)
+	var details []string
+	if len(links) > 1 {
+		details = append(details, fmt.Sprintf("🔗 %d of %d links processed", summary.Links, len(links)))
+	}
+	for _, failed := range summary.FailedLinks {
+		details = append(details, fmt.Sprintf("⚠️ %s: %s", failed.Link.Raw, userErrorMessage(failed.Err)))
+	}
+	for _, partial := range summary.Partial {
+		details = append(details, partialReadNote(partial))
+	}
+	if summary.Unavailable > 0 {
+		market := opts.Market
+		if market == "" {
+			market = h.processor.GetSpotifyClient().DefaultMarket()
+		}
+		details = append(details, fmt.Sprintf("🌍 %d tracks are unavailable in market %s", summary.Unavailable, market))
+	}
+	if summary.NoArtwork > 0 {
+		details = append(details, fmt.Sprintf("🖼 %d tracks have no artwork", summary.NoArtwork))
+	}
+	for _, raw := range skipped {
+		details = append(details, fmt.Sprintf("⚠️ Unsupported link skipped: %s", raw))
+	}
+	if stickers != nil {
+		packURL, packDetails := stickers.Result()
+		if packURL != "" {
+			details = append(details, "🧩 Your sticker pack: "+packURL)
+		}
+		details = append(details, packDetails...)
+	}
+
+	h.sender.SendFinalMessage(c.Chat().ID, username, finalCount, details)
 
 	return nil
 }
 
+// HandleResolution shows or changes the user's default cover size.
+func (h *Handlers) HandleResolution(c tele.Context) error {
+	value := strings.TrimSpace(c.Message().Payload)
+	if value == "" {
+		current := h.settings.Get(c.Sender().ID).Resolution
+		return c.Send(fmt.Sprintf("🖼 Your cover size: %s\n\nChange it with /resolution thumbnail, standard, largest or original.", current))
+	}
+
+	res, ok := spotify.ParseResolution(value)
+	if !ok {
+		return c.Send(fmt.Sprintf("❌ Unknown resolution %q. Use thumbnail, standard, largest or original.", value))
+	}
+	if err := h.settings.Update(c.Sender().ID, func(s *UserSettings) { s.Resolution = res }); err != nil {
+		log.Error().Err(err).Int64("user_id", c.Sender().ID).Msg("Failed to save user settings")
+		return c.Send("❌ Failed to save your settings, please try again later.")
+	}
+
+	log.Info().Int64("user_id", c.Sender().ID).Str("resolution", string(res)).Msg("Resolution changed")
+	return c.Send(fmt.Sprintf("✅ Covers will now be sent in %s size.", res))
+}
+
+// HandleCaption shows or changes the user's caption setting.
+func (h *Handlers) HandleCaption(c tele.Context) error {
+	value := strings.TrimSpace(c.Message().Payload)
+	if value == "" {
+		current := h.settings.Get(c.Sender().ID).Caption
+		return c.Send(fmt.Sprintf("📝 Your captions: %s\n\n"+
+			"Use /caption on, /caption off, or /caption followed by your own template.\n"+
+			"Placeholders: {album} {artists} {artist} {year} {date} {label} {type} {tracks} {link} {index} {total}", current))
+	}
+
+	setting := value
+	switch strings.ToLower(value) {
+	case captionOn, captionOff:
+		setting = strings.ToLower(value)
+	default:
+		if utf8.RuneCountInString(value) > maxCaptionLength {
+			return c.Send(fmt.Sprintf("❌ The template is too long: Telegram captions hold up to %d characters.", maxCaptionLength))
+		}
+	}
+	if err := h.settings.Update(c.Sender().ID, func(s *UserSettings) { s.Caption = setting }); err != nil {
+		log.Error().Err(err).Int64("user_id", c.Sender().ID).Msg("Failed to save user settings")
+		return c.Send("❌ Failed to save your settings, please try again later.")
+	}
+
+	log.Info().Int64("user_id", c.Sender().ID).Str("caption", setting).Msg("Caption setting changed")
+	switch setting {
+	case captionOn:
+		return c.Send("✅ Covers will be captioned with album, artists, year and a link.")
+	case captionOff:
+		return c.Send("✅ Captions are off.")
+	}
+	return c.Send("✅ Covers will be captioned with your template.")
+}
+
+// HandleMode shows or changes how covers are delivered.
+func (h *Handlers) HandleMode(c tele.Context) error {
+	value := strings.TrimSpace(c.Message().Payload)
+	if value == "" {
+		current := h.settings.Get(c.Sender().ID).Delivery
+		return c.Send(fmt.Sprintf("📬 Your delivery mode: %s\n\nChange it with /mode %s.", current, strings.Join(deliveryModes, ", /mode ")))
+	}
+
+	mode, ok := parseDelivery(value)
+	if mode == deliverySticker {
+		return c.Send("🧩 Sticker packs are made per link, send /stickers followed by a playlist, album or artist link.")
+	}
+	if !ok {
+		return c.Send(fmt.Sprintf("❌ Unknown delivery mode %q. Use %s.", value, strings.Join(deliveryModes, ", ")))
+	}
+	if err := h.settings.Update(c.Sender().ID, func(s *UserSettings) { s.Delivery = mode }); err != nil {
+		log.Error().Err(err).Int64("user_id", c.Sender().ID).Msg("Failed to save user settings")
+		return c.Send("❌ Failed to save your settings, please try again later.")
+	}
+
+	log.Info().Int64("user_id", c.Sender().ID).Str("delivery", mode).Msg("Delivery mode changed")
+	return c.Send(fmt.Sprintf("✅ Covers will now be delivered as %s.", deliveryDescriptions[mode]))
+}
+
+// HandleFilename shows or changes the template for names of covers sent as files.
+func (h *Handlers) HandleFilename(c tele.Context) error {
+	value := strings.TrimSpace(c.Message().Payload)
+	if value == "" {
+		current := h.settings.Get(c.Sender().ID).Filename
+		return c.Send(fmt.Sprintf("📄 Your file names: %s\n\n"+
+			"Change it with /filename followed by a template, e.g. /filename {index:03} {album}.jpg\n"+
+			"Placeholders: {album} {artists} {artist} {year} {date} {label} {type} {index} {total} {track_id}", current))
+	}
+
+	if err := h.settings.Update(c.Sender().ID, func(s *UserSettings) { s.Filename = value }); err != nil {
+		log.Error().Err(err).Int64("user_id", c.Sender().ID).Msg("Failed to save user settings")
+		return c.Send("❌ Failed to save your settings, please try again later.")
+	}
+
+	log.Info().Int64("user_id", c.Sender().ID).Str("filename", value).Msg("Filename template changed")
+	return c.Send("✅ Files will be named with your template.")
+}
+
+// parseLinks parses raw links, dropping duplicates. Links that cannot be
+// parsed are returned as skipped.
+func (h *Handlers) parseLinks(ctx context.Context, rawLinks []string) ([]spotify.Link, []string) {
+	var links []spotify.Link
+	var skipped []string
+	seen := make(map[string]bool)
+
+	for _, raw := range rawLinks {
+		link, err := h.processor.GetSpotifyClient().ParseLink(ctx, raw)
+		if err != nil {
+			log.Debug().Err(err).Str("url", raw).Msg("Failed to parse link")
+			skipped = append(skipped, raw)
+			continue
+		}
+		if seen[link.URL()] {
+			continue
+		}
+		seen[link.URL()] = true
+		links = append(links, link)
+	}
+
+	return links, skipped
+}
+
 func (h *Handlers) HandleInlineQuery(c tele.Context) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
 	defer cancel()
@@ -216,7 +555,7 @@ func (h *Handlers) HandleInlineQuery(c tele.Context) error {
 		article := &tele.ArticleResult{
 			Title:       "How to use",
 			Description: "Paste a Spotify link to get covers",
-			Text:        "Send any Spotify track, album, or playlist link to get high-quality cover images!",
+			Text:        "Send any Spotify track, album, playlist, artist, or podcast link to get high-quality cover images!",
 			ThumbURL:    "https://storage.googleapis.com/pr-newsroom-wp/1/2018/11/Spotify_Logo_RGB_Green.png",
 		}
 		article.SetResultID("help")
@@ -227,8 +566,8 @@ func (h *Handlers) HandleInlineQuery(c tele.Context) error {
 		})
 	}
 
-	spotifyURL := spotify.FindSpotifyURL(query)
-	if spotifyURL == "" {
+	rawLinks := spotify.FindLinks(query)
+	if len(rawLinks) == 0 {
 		article := &tele.ArticleResult{
 			Title:       "❌ Invalid link",
 			Description: "Please paste a valid Spotify URL",
@@ -238,28 +577,35 @@ func (h *Handlers) HandleInlineQuery(c tele.Context) error {
 		return c.Answer(&tele.QueryResponse{Results: tele.Results{article}, CacheTime: 10})
 	}
 
-	urlType := spotify.DetectURLType(spotifyURL)
-	if urlType == "unknown" {
+	link, err := h.processor.GetSpotifyClient().ParseLink(ctx, rawLinks[0])
+	if err != nil {
+		log.Debug().Err(err).Str("url", rawLinks[0]).Msg("Failed to parse inline link")
 		article := &tele.ArticleResult{
 			Title:       "❌ Unsupported link",
-			Description: "Only tracks, albums, and playlists are supported",
+			Description: "Only tracks, albums, playlists, artists, and podcasts are supported",
 			Text:        "This type of Spotify link is not supported.",
 		}
 		article.SetResultID("error_unsupported")
 		return c.Answer(&tele.QueryResponse{Results: tele.Results{article}, CacheTime: 10})
 	}
 
-	cacheKey := spotifyURL
+	opts, err := processOptions(parseRequestOptions(query), h.settings.Get(c.Sender().ID))
+	if err != nil {
+		article := &tele.ArticleResult{Title: "❌ Invalid option", Description: err.Error(), Text: "Invalid option: " + err.Error()}
+		article.SetResultID("error_option")
+		return c.Answer(&tele.QueryResponse{Results: tele.Results{article}, CacheTime: 10})
+	}
+	cacheKey := link.URL() + " " + strings.Join(opts.AlbumGroups, ",") + " " + opts.Market + " " + string(opts.Resolution)
 	var images []*spotify.ImageData
 
 	if cachedImages, found := inlineCacheInstance.Get(cacheKey); found {
 		images = cachedImages
 		log.Debug().Int("cached_count", len(images)).Msg("Using cached inline results")
 	} else {
-		tracks, _, _, err := h.processor.GetSpotifyClient().GetTracks(ctx, spotifyURL)
+		tracks, err := h.processor.GetSpotifyClient().GetTracks(ctx, link, opts.FetchOptions)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to get tracks for inline")
-			article := &tele.ArticleResult{Title: "❌ Error", Description: err.Error(), Text: "Failed to process"}
+			article := &tele.ArticleResult{Title: "❌ Error", Description: userErrorMessage(err), Text: "Failed to process"}
 			article.SetResultID("error")
 			return c.Answer(&tele.QueryResponse{Results: tele.Results{article}, CacheTime: 10})
 		}
@@ -269,11 +615,13 @@ func (h *Handlers) HandleInlineQuery(c tele.Context) error {
 			if len(track.Album.Images) == 0 {
 				continue
 			}
-			imageURL := track.Album.Images[0].URL
+			imageURL, _ := spotify.PickImage(track.Album.Images, opts.Resolution)
 			if _, exists := uniqueImages[imageURL]; !exists {
 				uniqueImages[imageURL] = &spotify.ImageData{
 					URL:     imageURL,
 					TrackID: track.ID,
+					Album:   track.Album,
+					Artists: track.CoverArtists(),
 				}
 			}
 		}
@@ -303,9 +651,10 @@ func (h *Handlers) HandleInlineQuery(c tele.Context) error {
 	timestamp := time.Now().UnixNano()
 
 	for idx, img := range images {
+		thumbURL, _ := spotify.PickImage(img.Album.Images, spotify.ResolutionThumbnail)
 		photoResult := &tele.PhotoResult{
 			URL:      img.URL,
-			ThumbURL: img.URL,
+			ThumbURL: thumbURL,
 		}
 		photoResult.SetResultID(fmt.Sprintf("p_%s_%d_%d", img.TrackID, timestamp, idx))
 
@@ -314,7 +663,7 @@ func (h *Handlers) HandleInlineQuery(c tele.Context) error {
 
 	log.Info().
 		Int64("user_id", c.Sender().ID).
-		Str("url", spotifyURL).
+		Str("url", link.Raw).
 		Int("results", len(results)).
 		Msg("Inline query processed")
 
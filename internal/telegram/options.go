@@ -0,0 +1,159 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"image2spotify/internal/processor"
+	"image2spotify/internal/spotify"
+)
+
+// parseRequestOptions collects "key=value" words that follow the link,
+// e.g. "https://open.spotify.com/artist/... groups=album,single market=JP".
+func parseRequestOptions(text string) map[string]string {
+	opts := make(map[string]string)
+	for _, word := range strings.Fields(text) {
+		key, value, ok := strings.Cut(word, "=")
+		// Query strings of links (?si=...) are not options
+		if !ok || key == "" || strings.ContainsAny(key, "/?&") {
+			continue
+		}
+		opts[strings.ToLower(key)] = value
+	}
+	return opts
+}
+
+func fetchOptions(opts map[string]string) (spotify.FetchOptions, error) {
+	var fetchOpts spotify.FetchOptions
+	if value, ok := opts["groups"]; ok {
+		groups := spotify.ParseAlbumGroups(value)
+		if len(groups) == 0 {
+			return fetchOpts, fmt.Errorf("unknown album groups %q, use groups=album,single,compilation,appears_on", value)
+		}
+		fetchOpts.AlbumGroups = groups
+	}
+	if value, ok := opts["market"]; ok {
+		market, valid := spotify.ParseMarket(value)
+		if !valid {
+			return fetchOpts, fmt.Errorf("unknown market %q, use a two-letter country code like market=JP", value)
+		}
+		fetchOpts.Market = market
+	}
+	return fetchOpts, nil
+}
+
+// Delivery modes
+const (
+	deliveryPhoto     = "photo"     // every cover as its own photo message
+	deliveryAlbum     = "album"     // covers grouped into media groups
+	deliveryDocument  = "document"  // uncompressed files with the original bytes
+	deliveryArchive   = "archive"   // ZIP files with a manifest
+	deliveryCollage   = "collage"   // covers composed into grids or a poster
+	deliveryPalette   = "palette"   // every cover with its color palette
+	deliverySlideshow = "slideshow" // covers animated into a GIF
+	deliverySticker   = "sticker"   // covers added to a new sticker pack
+)
+
+var deliveryModes = []string{deliveryPhoto, deliveryAlbum, deliveryDocument, deliveryArchive, deliveryCollage, deliveryPalette, deliverySlideshow}
+
+var deliveryDescriptions = map[string]string{
+	deliveryPhoto:     "separate photos",
+	deliveryAlbum:     "albums",
+	deliveryDocument:  "uncompressed files",
+	deliveryArchive:   "ZIP archives",
+	deliveryCollage:   "collages",
+	deliveryPalette:   "color palettes",
+	deliverySlideshow: "GIF slideshows",
+}
+
+// parseDelivery accepts a delivery mode name, singular or plural.
+func parseDelivery(value string) (string, bool) {
+	value = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), "s")
+	switch value {
+	case "file", "doc", "original":
+		return deliveryDocument, true
+	case "zip":
+		return deliveryArchive, true
+	case "grid", "poster", "mosaic":
+		return deliveryCollage, true
+	case "color", "colour", "colors", "colours":
+		return deliveryPalette, true
+	case "gif", "animation", "preview":
+		return deliverySlideshow, true
+	case deliverySticker, "pack", "stickerpack":
+		// Only per request: a pack is created for every link
+		return deliverySticker, true
+	}
+	for _, mode := range deliveryModes {
+		if value == mode {
+			return mode, true
+		}
+	}
+	return "", false
+}
+
+func defaultDelivery(value string) string {
+	if mode, ok := parseDelivery(value); ok {
+		return mode
+	}
+	return deliveryPhoto
+}
+
+// requestDelivery returns the delivery mode for a request: "as=album"
+// overrides the user's setting.
+func requestDelivery(opts map[string]string, settings UserSettings) (string, error) {
+	value, ok := opts["as"]
+	if !ok {
+		return settings.Delivery, nil
+	}
+	mode, valid := parseDelivery(value)
+	if !valid {
+		return "", fmt.Errorf("unknown delivery mode %q, use as=%s", value, strings.Join(deliveryModes, ", as="))
+	}
+	return mode, nil
+}
+
+// requestCaption returns the caption setting for a request: "caption=on" or
+// "caption=off" override the user's setting.
+func requestCaption(opts map[string]string, settings UserSettings) (string, error) {
+	value, ok := opts["caption"]
+	if !ok {
+		return settings.Caption, nil
+	}
+	switch strings.ToLower(value) {
+	case captionOn, "yes", "true":
+		// Keep a custom template if the user has one
+		if settings.Caption != captionOff {
+			return settings.Caption, nil
+		}
+		return captionOn, nil
+	case captionOff, "no", "false":
+		return captionOff, nil
+	}
+	return "", fmt.Errorf("unknown caption option %q, use caption=on or caption=off", value)
+}
+
+// processOptions combines the options given with a link and the user's settings.
+func processOptions(opts map[string]string, settings UserSettings) (processor.Options, error) {
+	fetchOpts, err := fetchOptions(opts)
+	if err != nil {
+		return processor.Options{}, err
+	}
+
+	procOpts := processor.Options{
+		FetchOptions: fetchOpts,
+		Resolution:   settings.Resolution,
+	}
+	value, ok := opts["res"]
+	if !ok {
+		value, ok = opts["resolution"]
+	}
+	if ok {
+		res, valid := spotify.ParseResolution(value)
+		if !valid {
+			return procOpts, fmt.Errorf("unknown resolution %q, use thumbnail, standard, largest or original", value)
+		}
+		procOpts.Resolution = res
+	}
+	return procOpts, nil
+}
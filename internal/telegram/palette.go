@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"image2spotify/internal/imaging"
+	"image2spotify/internal/spotify"
+)
+
+// paletteCardWidth is the width of palette cards, the size of API covers
+const paletteCardWidth = 640
+
+// SendPalette sends the cover with its palette band below and the color
+// codes in the caption.
+func (s *Sender) SendPalette(chatID int64, img *spotify.ImageData) error {
+	decoded, err := imaging.Decode(img.Data)
+	if err != nil {
+		return fmt.Errorf("decode cover: %w", err)
+	}
+
+	swatches := imaging.Palette(decoded, imaging.PaletteSize)
+	img.Palette = nil
+	for _, swatch := range swatches {
+		img.Palette = append(img.Palette, swatch.Color)
+	}
+
+	data, err := imaging.EncodeJPEG(imaging.PaletteCard(decoded, swatches, paletteCardWidth), 92)
+	if err != nil {
+		return err
+	}
+	return s.SendPicture(chatID, data, paletteCaption(img, swatches))
+}
+
+// paletteCaption lists the album and the colors with their shares.
+func paletteCaption(img *spotify.ImageData, swatches []imaging.Swatch) string {
+	var b strings.Builder
+	b.WriteString("🎨 ")
+	b.WriteString(img.Album.Name)
+	if len(img.Artists) > 0 {
+		b.WriteString(" — ")
+		b.WriteString(img.Artists[0].Name)
+	}
+	for _, swatch := range swatches {
+		fmt.Fprintf(&b, "\n%s  %2.0f%%", imaging.Hex(swatch.Color), swatch.Share*100)
+	}
+	return b.String()
+}
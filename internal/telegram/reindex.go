@@ -0,0 +1,210 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"image2spotify/internal/spotify"
+
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v4"
+)
+
+// coverTagPrefix starts the hashtag of every cover uploaded to the log channel
+const coverTagPrefix = "#cover_"
+
+const reindexUsage = "Send /reindex to rebuild the file_id cache from the log channel, " +
+	"or /reindex 1500 to start from message 1500."
+
+var (
+	errReindexRunning  = errors.New("reindex is already running")
+	errReindexDisabled = errors.New("the log channel or the file_id cache is off")
+)
+
+// coverTag is the caption of a cover uploaded to the log channel: a hashtag
+// with the image ID, to find the cover in the channel, and the URL the cover
+// is cached under, the key /reindex restores.
+func coverTag(img *spotify.ImageData) string {
+	url := img.CacheURL()
+	if url == "" {
+		return ""
+	}
+	if id := spotify.ImageID(url); id != "" {
+		return coverTagPrefix + id + "\n" + url
+	}
+	return url
+}
+
+// coverTagURL returns the image URL from a caption written by coverTag.
+func coverTagURL(caption string) string {
+	for _, line := range strings.Split(caption, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "https://") || strings.HasPrefix(line, "http://") {
+			return line
+		}
+	}
+	return ""
+}
+
+// reindexProgress counts the log channel messages looked at by ReindexLogChannel.
+type reindexProgress struct {
+	Scanned int // messages looked at
+	Total   int // messages to look at
+	Indexed int // tagged photos stored in the cache
+	Skipped int // deleted messages, documents and untagged photos
+}
+
+// ReindexLogChannel rebuilds the file_id cache from the covers in the log
+// channel, from message from up to the newest one. The Bot API can't read a
+// channel's history, so every message is forwarded into the channel itself
+// to read its photo and caption, and the copy is deleted right away.
+func (s *Sender) ReindexLogChannel(from int, onProgress func(reindexProgress)) (reindexProgress, error) {
+	var progress reindexProgress
+	if s.logChannelID == 0 || s.fileIDs == nil {
+		return progress, errReindexDisabled
+	}
+	if !s.reindexing.CompareAndSwap(false, true) {
+		return progress, errReindexRunning
+	}
+	defer s.reindexing.Store(false)
+
+	// ID служебного сообщения — верхняя граница: все обложки загружены до него
+	logChannel := &tele.Chat{ID: s.logChannelID}
+	var probe *tele.Message
+	var probeBot *tele.Bot
+	err := s.channelRequest("probe", func(bot *tele.Bot) (err error) {
+		probe, err = bot.Send(logChannel, "🔄 Rebuilding the file_id cache...", tele.Silent)
+		probeBot = bot
+		return err
+	})
+	if err != nil {
+		return progress, err
+	}
+	defer s.deleteChannelMessage(probeBot, probe)
+
+	progress.Total = max(probe.ID-from, 0)
+	for id := from; id < probe.ID; id++ {
+		stored := tele.StoredMessage{MessageID: strconv.Itoa(id), ChatID: s.logChannelID}
+		var forwarded *tele.Message
+		err := s.channelRequest("forward", func(bot *tele.Bot) (err error) {
+			if forwarded, err = bot.Forward(logChannel, stored, tele.Silent); err == nil {
+				s.deleteChannelMessage(bot, forwarded)
+			}
+			return err
+		})
+
+		if err != nil && !forwardSkippable(err) {
+			return progress, fmt.Errorf("message %d: %w", id, err)
+		}
+
+		progress.Scanned++
+		switch {
+		case err != nil:
+			progress.Skipped++
+		case forwarded.Photo == nil || coverTagURL(forwarded.Caption) == "":
+			progress.Skipped++
+		default:
+			s.fileIDs.Put(&spotify.ImageData{URL: coverTagURL(forwarded.Caption)}, forwarded.Photo.FileID)
+			progress.Indexed++
+		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+
+	log.Info().
+		Int("from", from).
+		Int("scanned", progress.Scanned).
+		Int("indexed", progress.Indexed).
+		Int("skipped", progress.Skipped).
+		Msg("Log channel reindexed")
+	return progress, nil
+}
+
+// channelRequest выполняет запрос к лог-каналу через очередного бота с
+// повторами при FloodWait: в канал можно писать примерно 20 сообщений в минуту
+func (s *Sender) channelRequest(action string, request func(bot *tele.Bot) error) error {
+	return s.retryFloodWait("log channel "+action, maxReindexRetries, func() error {
+		bot, worker := s.channelBot()
+		err := request(bot)
+		if worker != nil {
+			if err == nil {
+				atomic.StoreInt32(&worker.failures, 0)
+			} else if isFloodWait(err) {
+				atomic.AddInt32(&worker.failures, 1)
+			}
+		}
+		return err
+	})
+}
+
+// deleteChannelMessage удаляет служебное сообщение из лог-канала тем же
+// ботом, который его отправил
+func (s *Sender) deleteChannelMessage(bot *tele.Bot, msg *tele.Message) {
+	if msg == nil {
+		return
+	}
+	if err := bot.Delete(msg); err != nil {
+		log.Warn().Err(err).Int("message_id", msg.ID).Msg("Failed to delete message from log channel")
+	}
+}
+
+// forwardSkippable reports an error about a message that is gone or can't be
+// forwarded, such as a deleted post or a service message.
+func forwardSkippable(err error) bool {
+	text := strings.ToLower(err.Error())
+	return strings.Contains(text, "message to forward not found") ||
+		strings.Contains(text, "message_id_invalid") ||
+		strings.Contains(text, "can't be forwarded")
+}
+
+// HandleReindex rebuilds the file_id cache from the log channel. It is
+// available to the users in ADMIN_USER_IDS only.
+func (h *Handlers) HandleReindex(c tele.Context) error {
+	if !h.admins[c.Sender().ID] {
+		return c.Send("⛔ This command is only available to the bot admins.")
+	}
+
+	from := 1
+	if payload := strings.TrimSpace(c.Message().Payload); payload != "" {
+		n, err := strconv.Atoi(payload)
+		if err != nil || n < 1 {
+			return c.Send(reindexUsage)
+		}
+		from = n
+	}
+
+	progressMsg, _ := c.Bot().Send(c.Sender(), "🔄 Rebuilding the file_id cache from the log channel...")
+	report := func(text string) error {
+		if progressMsg != nil {
+			_, err := c.Bot().Edit(progressMsg, text)
+			return err
+		}
+		return c.Send(text)
+	}
+
+	lastUpdate := time.Now()
+	progress, err := h.sender.ReindexLogChannel(from, func(p reindexProgress) {
+		if time.Since(lastUpdate) >= 3*time.Second && progressMsg != nil {
+			c.Bot().Edit(progressMsg, fmt.Sprintf("🔄 Reindexing: %d/%d messages, %d covers indexed",
+				p.Scanned, p.Total, p.Indexed))
+			lastUpdate = time.Now()
+		}
+	})
+
+	summary := fmt.Sprintf("%d/%d messages scanned, %d covers indexed, %d skipped",
+		progress.Scanned, progress.Total, progress.Indexed, progress.Skipped)
+	switch {
+	case errors.Is(err, errReindexDisabled), errors.Is(err, errReindexRunning):
+		return report("❌ Can't reindex: " + err.Error() + ".")
+	case err != nil:
+		log.Error().Err(err).Int("from", from).Msg("Failed to reindex log channel")
+		return report(fmt.Sprintf("❌ Reindex stopped: %s\n%s\n\nSend /reindex %d to continue.",
+			err.Error(), summary, from+progress.Scanned))
+	}
+	return report("✅ File_id cache rebuilt: " + summary)
+}
@@ -2,12 +2,14 @@ package telegram
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"image2spotify/internal/processor"
 	"image2spotify/internal/spotify"
 
 	"github.com/rs/zerolog/log"
@@ -21,23 +23,41 @@ type BotWorker struct {
 	failures     int32
 }
 
+// Число попыток одного запроса к Telegram. /reindex пишет в лог-канал без
+// перерыва и упирается в FloodWait постоянно, поэтому ему попыток нужно больше.
+const (
+	maxSendRetries    = 3
+	maxReindexRetries = 10
+)
+
 type Sender struct {
 	primaryBot      *tele.Bot
 	workerBots      []*BotWorker
 	currentWorker   int32
+	maxAlbumSize    int
 	maxFileSizeMB   int
 	messageInterval time.Duration
 	logChannelID    int64
+	fileIDs         *fileIDStore
+	download        func(ctx context.Context, imageURL, fallbackURL string) ([]byte, error)
+	reindexing      atomic.Bool
 	globalMu        sync.Mutex
 }
 
-func NewSender(primaryBot *tele.Bot, workerBotTokens []string, maxAlbumSize, maxFileSizeMB, maxMessagesPerSecond int, logChannelID int64) *Sender {
+func NewSender(primaryBot *tele.Bot, workerBotTokens []string, maxAlbumSize, maxFileSizeMB, maxMessagesPerSecond int, logChannelID int64, fileIDs *fileIDStore) *Sender {
 	s := &Sender{
 		primaryBot:      primaryBot,
 		workerBots:      make([]*BotWorker, 0, len(workerBotTokens)),
+		maxAlbumSize:    maxAlbumSize,
 		maxFileSizeMB:   maxFileSizeMB,
 		messageInterval: time.Second / time.Duration(maxMessagesPerSecond),
 		logChannelID:    logChannelID,
+		fileIDs:         fileIDs,
+	}
+
+	// В media group Telegram принимает от 2 до 10 элементов
+	if s.maxAlbumSize < 2 || s.maxAlbumSize > 10 {
+		s.maxAlbumSize = 10
 	}
 
 	// Initialize worker bots
@@ -72,6 +92,21 @@ func NewSender(primaryBot *tele.Bot, workerBotTokens []string, maxAlbumSize, max
 	return s
 }
 
+// SetDownloader sets the function used to download a cover again when
+// Telegram rejects its cached file_id.
+func (s *Sender) SetDownloader(download func(ctx context.Context, imageURL, fallbackURL string) ([]byte, error)) {
+	s.download = download
+}
+
+// FileIDs returns the lookup that lets the processor skip downloading covers
+// already sent as photos, or nil when the cache is off.
+func (s *Sender) FileIDs() processor.FileIDLookup {
+	if s.fileIDs == nil {
+		return nil
+	}
+	return s.fileIDs
+}
+
 // getNextWorker returns the next available worker bot using round-robin
 func (s *Sender) getNextWorker() *BotWorker {
 	if len(s.workerBots) == 0 {
@@ -83,7 +118,7 @@ func (s *Sender) getNextWorker() *BotWorker {
 	for i := 0; i < attempts; i++ {
 		idx := int(atomic.AddInt32(&s.currentWorker, 1)) % len(s.workerBots)
 		worker := s.workerBots[idx]
-		
+
 		// Пропускаем воркеров с большим количеством ошибок
 		if atomic.LoadInt32(&worker.failures) < 3 {
 			return worker
@@ -98,168 +133,450 @@ func (s *Sender) getNextWorker() *BotWorker {
 	return s.workerBots[0]
 }
 
-// StreamImage отправляет одно изображение сразу в канал и пользователю
-func (s *Sender) StreamImage(chatID int64, username string, img *spotify.ImageData, index, total int) error {
+// StreamImage отправляет одно изображение сразу в канал и пользователю.
+// Без caption каждое десятое фото подписывается номером "index/total".
+func (s *Sender) StreamImage(chatID int64, username string, img *spotify.ImageData, index, total int, caption *Caption) error {
+	return s.streamCover(chatID, img, false, index, total, caption)
+}
+
+// StreamDocument отправляет изображение файлом, без пережатия Telegram.
+// Путь тот же, что у StreamImage: лог-канал, затем пользователю по file_id.
+func (s *Sender) StreamDocument(chatID int64, username string, img *spotify.ImageData, index, total int, caption *Caption) error {
+	return s.streamCover(chatID, img, true, index, total, caption)
+}
+
+func (s *Sender) streamCover(chatID int64, img *spotify.ImageData, document bool, index, total int, caption *Caption) error {
+	if !s.sendable(img) {
+		return nil
+	}
+
+	// 1. Берём file_id из кэша или отправляем в лог-канал через worker bots (если доступны)
+	fileID := s.coverFileID(img, index, document)
+
+	// 2. Отправляем пользователю (через FileID если есть, иначе загружаем заново)
+	return s.sendCover(chatID, img, fileID, document, index, total, caption)
+}
+
+// coverFileID возвращает file_id обложки: сохранённый в кэше или полученный
+// при загрузке в лог-канал. Кэшируются только фото: документ при повторной
+// отправке сохранил бы старое имя файла.
+func (s *Sender) coverFileID(img *spotify.ImageData, index int, document bool) string {
+	if document {
+		return s.uploadToLogChannel(img, index, true)
+	}
+	if img.FileID != "" {
+		return img.FileID
+	}
+	if fileID := s.fileIDs.ByHash(img.Data); fileID != "" {
+		log.Debug().Str("track_id", img.TrackID).Str("file_id", fileID).Msg("Cover found in cache by hash")
+		return fileID
+	}
+
+	fileID := s.uploadToLogChannel(img, index, false)
+	s.fileIDs.Put(img, fileID)
+	return fileID
+}
+
+// reloadCover забывает отклонённый Telegram file_id и заново скачивает
+// обложку, чтобы отправить её байтами
+func (s *Sender) reloadCover(img *spotify.ImageData, fileID string) bool {
+	s.fileIDs.Invalidate(fileID)
+	img.FileID = ""
+	if len(img.Data) > 0 {
+		return true
+	}
+	if s.download == nil || img.URL == "" {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	data, err := s.download(ctx, img.URL, img.FallbackURL)
+	if err != nil || len(data) == 0 {
+		log.Error().Err(err).Str("url", img.URL).Msg("Failed to download cover after file_id was rejected")
+		return false
+	}
+	img.Data = data
+	return true
+}
+
+// sendCover отправляет пользователю одну обложку с повторами при FloodWait
+func (s *Sender) sendCover(chatID int64, img *spotify.ImageData, fileID string, document bool, index, total int, caption *Caption) error {
+	recipient := &tele.User{ID: chatID}
+
+	// Отклонённый file_id и ошибка подписи исправляются по одному разу,
+	// поэтому цикл конечен
+	for {
+		parseMode := tele.ModeDefault
+		captionText := ""
+		if caption != nil {
+			captionText = caption.Text
+			parseMode = caption.ParseMode
+		} else if index%10 == 1 {
+			captionText = fmt.Sprintf("%d/%d", index, total)
+		}
+
+		// Через FileID быстро, без FileID загружаем заново
+		var sent *tele.Message
+		err := s.retryFloodWait("user send", maxSendRetries, func() (err error) {
+			s.waitPrimary()
+			sent, err = s.primaryBot.Send(recipient, coverMedia(img, fileID, document, captionText), parseMode)
+			return err
+		})
+
+		if err == nil {
+			// Без лог-канала file_id берём из сообщения пользователю
+			if fileID == "" && !document {
+				s.fileIDs.Put(img, mediaFileID(sent))
+			}
+			log.Debug().
+				Int64("chat_id", chatID).
+				Str("track_id", img.TrackID).
+				Int("index", index).
+				Msg("Sent to user")
+			return nil
+		}
+
+		// Telegram не знает сохранённый file_id: отправляем обложку байтами
+		if fileID != "" && fileIDRejected(err) {
+			log.Warn().Err(err).Str("track_id", img.TrackID).Str("file_id", fileID).Msg("File_id rejected, uploading cover again")
+			if !s.reloadCover(img, fileID) {
+				return fmt.Errorf("file_id rejected and cover could not be downloaded: %w", err)
+			}
+			fileID = ""
+			continue
+		}
+
+		// Ошибка разметки в шаблоне подписи или слишком длинная подпись:
+		// отправляем обложку без подписи
+		if caption != nil && caption.Text != "" && captionRejected(err) {
+			log.Warn().Err(err).Str("caption", caption.Text).Msg("Invalid caption markup, sending without caption")
+			caption = &Caption{}
+			continue
+		}
+
+		log.Error().Err(err).Int64("chat_id", chatID).Str("track_id", img.TrackID).Msg("Failed to send to user")
+		return fmt.Errorf("failed to send image: %w", err)
+	}
+}
+
+// SendFile отправляет пользователю файл, например архив с обложками
+func (s *Sender) SendFile(chatID int64, data []byte, fileName, caption string) error {
+	return s.sendUpload(chatID, "file", fileName, len(data), func() tele.Sendable {
+		return &tele.Document{
+			File:     tele.FromReader(bytes.NewReader(data)),
+			FileName: fileName,
+			Caption:  caption,
+		}
+	})
+}
+
+// SendPicture отправляет пользователю изображение, нарисованное ботом (коллаж и т.п.)
+func (s *Sender) SendPicture(chatID int64, data []byte, caption string) error {
+	img := &spotify.ImageData{Data: data, TrackID: "rendered"}
+	return s.sendCover(chatID, img, "", false, 0, 0, &Caption{Text: caption})
+}
+
+// SendAnimation отправляет пользователю GIF, который Telegram показывает как анимацию
+func (s *Sender) SendAnimation(chatID int64, data []byte, fileName string, width, height int, caption string) error {
+	return s.sendUpload(chatID, "animation", fileName, len(data), func() tele.Sendable {
+		return &tele.Animation{
+			File:     tele.FromReader(bytes.NewReader(data)),
+			FileName: fileName,
+			Width:    width,
+			Height:   height,
+			Caption:  caption,
+		}
+	})
+}
+
+// sendUpload отправляет пользователю файл, собранный ботом. media создаёт
+// сообщение заново для каждой попытки: reader с байтами читается один раз.
+func (s *Sender) sendUpload(chatID int64, kind, fileName string, size int, media func() tele.Sendable) error {
+	recipient := &tele.User{ID: chatID}
+	err := s.retryFloodWait(kind+" send", maxSendRetries, func() error {
+		s.waitPrimary()
+		_, err := s.primaryBot.Send(recipient, media())
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", chatID).Str("file_name", fileName).Msg("Failed to send " + kind + " to user")
+		return err
+	}
+
+	log.Debug().
+		Int64("chat_id", chatID).
+		Str("file_name", fileName).
+		Int("size", size).
+		Msg("Sent " + kind + " to user")
+	return nil
+}
+
+// sendable проверяет, что изображение не пустое и укладывается в MaxFileSizeMB
+func (s *Sender) sendable(img *spotify.ImageData) bool {
 	maxFileSize := int64(s.maxFileSizeMB * 1024 * 1024)
 	if int64(len(img.Data)) > maxFileSize {
 		log.Debug().Str("track_id", img.TrackID).Int("size", len(img.Data)).Msg("Image exceeds size limit")
-		return nil
+		return false
 	}
 
-	if len(img.Data) == 0 {
+	// Обложка из кэша file_id приходит без байтов
+	if len(img.Data) == 0 && img.FileID == "" {
 		log.Debug().Str("track_id", img.TrackID).Msg("Empty image data")
+		return false
+	}
+
+	return true
+}
+
+// AlbumItem — обложка, ожидающая отправки в media group
+type AlbumItem struct {
+	Image   *spotify.ImageData
+	FileID  string // file_id из лог-канала, если загрузка удалась
+	Caption *Caption
+}
+
+// MaxAlbumSize возвращает размер media group
+func (s *Sender) MaxAlbumSize() int {
+	return s.maxAlbumSize
+}
+
+// SendAlbum отправляет пользователю до MaxAlbumSize обложек одним сообщением.
+// Одиночная обложка уходит обычным фото, потому что media group требует двух элементов.
+func (s *Sender) SendAlbum(chatID int64, items []AlbumItem) error {
+	if len(items) == 0 {
 		return nil
 	}
+	if len(items) == 1 {
+		return s.sendCover(chatID, items[0].Image, items[0].FileID, false, 0, 0, items[0].Caption)
+	}
 
-	var fileID string
-	maxRetries := 3
+	recipient := &tele.User{ID: chatID}
+	useCaptions := true
+
+	// Отклонённые file_id и ошибка подписей исправляются по одному разу
+	for {
+		// Альбом собирается заново для каждой попытки: reader с байтами читается один раз
+		var sent []tele.Message
+		err := s.retryFloodWait("album send", maxSendRetries, func() (err error) {
+			album, parseMode := albumMedia(items, useCaptions)
+			s.waitPrimary()
+			sent, err = s.primaryBot.SendAlbum(recipient, album, parseMode)
+			return err
+		})
 
-	// 1. Отправляем в лог-канал через worker bots (если доступны)
-	if s.logChannelID != 0 {
-		logChannel := &tele.Chat{ID: s.logChannelID}
-
-		for retry := 0; retry < maxRetries; retry++ {
-			worker := s.getNextWorker()
-			var bot *tele.Bot
-			
-			if worker != nil {
-				bot = worker.bot
-				
-				// Rate limiting для worker
-				worker.mu.Lock()
-				elapsed := time.Since(worker.lastSendTime)
-				if elapsed < s.messageInterval {
-					time.Sleep(s.messageInterval - elapsed)
+		if err == nil {
+			// Без лог-канала file_id берём из отправленного альбома
+			for i, item := range items {
+				if item.FileID == "" && i < len(sent) {
+					s.fileIDs.Put(item.Image, mediaFileID(&sent[i]))
 				}
-				worker.lastSendTime = time.Now()
-				worker.mu.Unlock()
-			} else {
-				// Fallback to primary bot
-				bot = s.primaryBot
-				
-				s.globalMu.Lock()
-				time.Sleep(s.messageInterval)
-				s.globalMu.Unlock()
-			}
-
-			// Отправляем БЕЗ caption
-			photo := &tele.Photo{
-				File: tele.FromReader(bytes.NewReader(img.Data)),
 			}
+			log.Debug().
+				Int64("chat_id", chatID).
+				Int("count", len(items)).
+				Msg("Sent album to user")
+			return nil
+		}
 
-			sent, err := bot.Send(logChannel, photo)
-			if err == nil {
-				if sent.Photo != nil && sent.Photo.FileID != "" {
-					fileID = sent.Photo.FileID
-					
-					// Сбрасываем счётчик ошибок при успехе
-					if worker != nil {
-						atomic.StoreInt32(&worker.failures, 0)
+		// Какой file_id отклонён, Telegram не сообщает: перезагружаем все
+		if fileIDRejected(err) && albumHasFileIDs(items) {
+			log.Warn().Err(err).Int("count", len(items)).Msg("File_id rejected in album, uploading covers again")
+			kept := items[:0]
+			for _, item := range items {
+				if item.FileID != "" {
+					if !s.reloadCover(item.Image, item.FileID) {
+						continue
 					}
-					
-					log.Debug().
-						Str("track_id", img.TrackID).
-						Int("index", index).
-						Str("file_id", fileID).
-						Msg("Uploaded to log channel")
+					item.FileID = ""
 				}
-				break
+				kept = append(kept, item)
 			}
-
-			// Увеличиваем счётчик ошибок
-			if worker != nil {
-				atomic.AddInt32(&worker.failures, 1)
+			items = kept
+			if len(items) < 2 {
+				return s.SendAlbum(chatID, items)
 			}
+			continue
+		}
 
-			// Обработка FloodWait
-			if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "retry after") {
-				waitTime := s.parseRetryAfter(err.Error())
-				if waitTime == 0 {
-					waitTime = time.Duration(retry+1) * 3 * time.Second
-				}
-				
-				log.Debug().
-					Err(err).
-					Int("retry", retry+1).
-					Dur("wait_time", waitTime).
-					Msg("FloodWait on log channel, switching worker")
-				
-				// При FloodWait сразу переключаемся на другого воркера
-				time.Sleep(1 * time.Second)
-				continue
-			}
+		// Ошибка разметки в шаблоне подписи или слишком длинная подпись:
+		// отправляем альбом без подписей
+		if useCaptions && captionRejected(err) {
+			log.Warn().Err(err).Msg("Invalid caption markup, sending album without captions")
+			useCaptions = false
+			continue
+		}
+
+		log.Error().Err(err).Int64("chat_id", chatID).Int("count", len(items)).Msg("Failed to send album to user")
+		return fmt.Errorf("failed to send album: %w", err)
+	}
+}
 
-			log.Error().Err(err).Int("retry", retry+1).Msg("Failed to send to log channel")
-			time.Sleep(time.Duration(retry+1) * time.Second)
+// albumMedia собирает media group: обложки с file_id отправляются по нему,
+// остальные загружаются байтами
+func albumMedia(items []AlbumItem, useCaptions bool) (tele.Album, tele.ParseMode) {
+	album := make(tele.Album, 0, len(items))
+	parseMode := tele.ModeDefault
+	for _, item := range items {
+		photo := &tele.Photo{}
+		if item.FileID != "" {
+			photo.File = tele.File{FileID: item.FileID}
+		} else {
+			photo.File = tele.FromReader(bytes.NewReader(item.Image.Data))
 		}
+		if useCaptions && item.Caption != nil {
+			photo.Caption = item.Caption.Text
+			parseMode = item.Caption.ParseMode
+		}
+		album = append(album, photo)
 	}
+	return album, parseMode
+}
 
-	// 2. Отправляем пользователю (через FileID если есть, иначе загружаем заново)
-	recipient := &tele.User{ID: chatID}
+func albumHasFileIDs(items []AlbumItem) bool {
+	for _, item := range items {
+		if item.FileID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadToLogChannel загружает изображение в лог-канал через worker bots
+// и возвращает его file_id, или пустую строку, если загрузить не удалось.
+// file_id фото нельзя отправить документом и наоборот, поэтому document
+// должен совпадать со способом отправки пользователю.
+func (s *Sender) uploadToLogChannel(img *spotify.ImageData, index int, document bool) string {
+	if s.logChannelID == 0 {
+		return ""
+	}
+
+	var fileID string
+	maxRetries := 3
+	logChannel := &tele.Chat{ID: s.logChannelID}
 
 	for retry := 0; retry < maxRetries; retry++ {
-		var sent *tele.Message
-		var err error
+		bot, worker := s.channelBot()
 
-		// Rate limiting для primary bot
-		s.globalMu.Lock()
-		time.Sleep(s.messageInterval)
-		s.globalMu.Unlock()
+		// Подпись с ID и URL обложки позволяет восстановить кэш по каналу (/reindex)
+		sent, err := bot.Send(logChannel, coverMedia(img, "", document, coverTag(img)))
+		if err == nil {
+			if id := mediaFileID(sent); id != "" {
+				fileID = id
 
-		if fileID != "" {
-			// Отправляем через FileID (быстро)
-			photo := &tele.Photo{
-				File: tele.File{FileID: fileID},
-			}
-			if index%10 == 1 {
-				photo.Caption = fmt.Sprintf("%d/%d", index, total)
-			}
-			sent, err = s.primaryBot.Send(recipient, photo)
-		} else {
-			// Загружаем заново если нет FileID
-			photo := &tele.Photo{
-				File: tele.FromReader(bytes.NewReader(img.Data)),
-			}
-			if index%10 == 1 {
-				photo.Caption = fmt.Sprintf("%d/%d", index, total)
+				// Сбрасываем счётчик ошибок при успехе
+				if worker != nil {
+					atomic.StoreInt32(&worker.failures, 0)
+				}
+
+				log.Debug().
+					Str("track_id", img.TrackID).
+					Int("index", index).
+					Str("file_id", fileID).
+					Msg("Uploaded to log channel")
 			}
-			sent, err = s.primaryBot.Send(recipient, photo)
+			break
 		}
 
-		if err == nil {
-			log.Debug().
-				Int64("chat_id", chatID).
-				Str("track_id", img.TrackID).
-				Int("index", index).
-				Msg("Sent to user")
-			return nil
+		// Увеличиваем счётчик ошибок
+		if worker != nil {
+			atomic.AddInt32(&worker.failures, 1)
 		}
 
 		// Обработка FloodWait
-		if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "retry after") {
+		if isFloodWait(err) {
 			waitTime := s.parseRetryAfter(err.Error())
 			if waitTime == 0 {
 				waitTime = time.Duration(retry+1) * 3 * time.Second
 			}
-			log.Warn().
+
+			log.Debug().
 				Err(err).
 				Int("retry", retry+1).
 				Dur("wait_time", waitTime).
-				Msg("FloodWait on user send")
-			time.Sleep(waitTime)
+				Msg("FloodWait on log channel, switching worker")
 
-			// При FloodWait пробуем через FileID если есть
-			if fileID == "" && sent != nil && sent.Photo != nil {
-				fileID = sent.Photo.FileID
-			}
+			// При FloodWait сразу переключаемся на другого воркера
+			time.Sleep(1 * time.Second)
 			continue
 		}
 
-		log.Error().Err(err).Int("retry", retry+1).Msg("Failed to send to user")
+		log.Error().Err(err).Int("retry", retry+1).Msg("Failed to send to log channel")
 		time.Sleep(time.Duration(retry+1) * time.Second)
 	}
 
-	return fmt.Errorf("failed to send image after %d retries", maxRetries)
+	return fileID
+}
+
+// channelBot выбирает бота для запроса в лог-канал и выдерживает интервал
+// между сообщениями. worker равен nil, когда запрос идёт через primary bot.
+func (s *Sender) channelBot() (bot *tele.Bot, worker *BotWorker) {
+	worker = s.getNextWorker()
+	if worker == nil {
+		// Fallback to primary bot
+		s.waitPrimary()
+		return s.primaryBot, nil
+	}
+
+	// Rate limiting для worker
+	worker.mu.Lock()
+	elapsed := time.Since(worker.lastSendTime)
+	if elapsed < s.messageInterval {
+		time.Sleep(s.messageInterval - elapsed)
+	}
+	worker.lastSendTime = time.Now()
+	worker.mu.Unlock()
+	return worker.bot, worker
+}
+
+// waitPrimary выдерживает интервал между сообщениями primary bot
+func (s *Sender) waitPrimary() {
+	s.globalMu.Lock()
+	time.Sleep(s.messageInterval)
+	s.globalMu.Unlock()
+}
+
+// retryFloodWait выполняет запрос к Telegram, делая до attempts попыток:
+// при FloodWait ждёт время, названное Telegram, при сетевой ошибке делает
+// короткую паузу.
+// Остальные ответы API (неверный file_id, разметка подписи и т.п.) повтором
+// не исправить, они возвращаются сразу и разбираются вызывающим кодом.
+func (s *Sender) retryFloodWait(action string, attempts int, request func() error) error {
+	var err error
+	for retry := 0; retry < attempts; retry++ {
+		if err = request(); err == nil {
+			return nil
+		}
+
+		var waitTime time.Duration
+		switch {
+		case isFloodWait(err):
+			waitTime = s.parseRetryAfter(err.Error())
+			if waitTime == 0 {
+				waitTime = time.Duration(retry+1) * 3 * time.Second
+			}
+			log.Warn().
+				Err(err).
+				Int("retry", retry+1).
+				Dur("wait_time", waitTime).
+				Str("action", action).
+				Msg("FloodWait, waiting before retry")
+		case strings.HasPrefix(err.Error(), "telegram: "):
+			return err
+		default:
+			waitTime = time.Duration(retry+1) * time.Second
+			log.Warn().Err(err).Int("retry", retry+1).Str("action", action).Msg("Telegram request failed, retrying")
+		}
+		if retry < attempts-1 {
+			time.Sleep(waitTime)
+		}
+	}
+	return fmt.Errorf("%s failed after %d retries: %w", action, attempts, err)
+}
+
+// isFloodWait проверяет, что Telegram ответил 429 Too Many Requests
+func isFloodWait(err error) bool {
+	return strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "retry after")
 }
 
 func (s *Sender) parseRetryAfter(errMsg string) time.Duration {
@@ -282,9 +599,13 @@ func (s *Sender) SendText(chatID int64, text string) error {
 	return err
 }
 
-func (s *Sender) SendFinalMessage(chatID int64, username string, total int) {
+// SendFinalMessage sends the job summary; details are appended one per line.
+func (s *Sender) SendFinalMessage(chatID int64, username string, total int, details []string) {
 	recipient := &tele.User{ID: chatID}
 	msg := fmt.Sprintf("✅ Successfully sent %d covers!", total)
+	if len(details) > 0 {
+		msg += "\n\n" + strings.Join(details, "\n")
+	}
 	s.primaryBot.Send(recipient, msg)
 }
 
@@ -293,4 +614,7 @@ func (s *Sender) Shutdown() {
 		worker.bot.Stop()
 		log.Info().Int("worker_id", i).Msg("Worker bot stopped")
 	}
+	if err := s.fileIDs.Close(); err != nil {
+		log.Warn().Err(err).Msg("Failed to close file_id cache")
+	}
 }
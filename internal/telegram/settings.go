@@ -0,0 +1,88 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"image2spotify/internal/spotify"
+
+	"github.com/rs/zerolog/log"
+)
+
+// UserSettings holds per-user defaults. Options given with a link override them.
+type UserSettings struct {
+	Resolution spotify.Resolution `json:"resolution,omitempty"`
+	Caption    string             `json:"caption,omitempty"` // "on", "off" or a custom template
+	Delivery   string             `json:"delivery,omitempty"`
+	Filename   string             `json:"filename,omitempty"` // file name template
+}
+
+// settingsStore keeps user settings in a JSON file next to the bot.
+type settingsStore struct {
+	mu       sync.RWMutex
+	path     string
+	defaults UserSettings
+	users    map[int64]UserSettings
+}
+
+func newSettingsStore(path string, defaults UserSettings) *settingsStore {
+	s := &settingsStore{
+		path:     path,
+		defaults: defaults,
+		users:    make(map[int64]UserSettings),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to read user settings")
+		}
+		return s
+	}
+	if err := json.Unmarshal(data, &s.users); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to parse user settings")
+		s.users = make(map[int64]UserSettings)
+	}
+	log.Info().Int("users", len(s.users)).Msg("User settings loaded")
+
+	return s
+}
+
+// Get returns the user's settings with unset fields filled from the defaults.
+func (s *settingsStore) Get(userID int64) UserSettings {
+	s.mu.RLock()
+	settings := s.users[userID]
+	s.mu.RUnlock()
+
+	if settings.Resolution == "" {
+		settings.Resolution = s.defaults.Resolution
+	}
+	if settings.Caption == "" {
+		settings.Caption = s.defaults.Caption
+	}
+	if settings.Delivery == "" {
+		settings.Delivery = s.defaults.Delivery
+	}
+	if settings.Filename == "" {
+		settings.Filename = s.defaults.Filename
+	}
+	return settings
+}
+
+// Update changes the user's settings and saves the file.
+func (s *settingsStore) Update(userID int64, update func(*UserSettings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := s.users[userID]
+	update(&settings)
+	s.users[userID] = settings
+
+	data, err := json.MarshalIndent(s.users, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
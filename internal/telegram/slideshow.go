@@ -0,0 +1,239 @@
+package telegram
+
+import (
+	"fmt"
+	"image/gif"
+	"strconv"
+	"strings"
+	"time"
+
+	"image2spotify/internal/imaging"
+	"image2spotify/internal/spotify"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Slideshow limits
+const (
+	maxSlideshowCovers   = 500
+	maxSlideshowFrames   = 200
+	defaultSlideshowSize = 480
+	minSlideshowSize     = 128
+	maxSlideshowSize     = 720
+	defaultFrameDuration = 500 * time.Millisecond
+	minFrameDuration     = 50 * time.Millisecond
+	maxFrameDuration     = 10 * time.Second
+	maxSlideshowLength   = 5 * time.Minute
+	slideshowSizeReserve = 64 * 1024
+)
+
+// slideshowSettings are the options of one slideshow request.
+type slideshowSettings struct {
+	Frame  time.Duration // how long every cover is shown
+	Length time.Duration // total length; when set it overrides Frame
+	Size   int
+	Order  string
+}
+
+// parseSlideshowSettings reads "frame=0.5s", "length=10s", "size=480" and
+// "order=color". Durations without a unit are seconds.
+func parseSlideshowSettings(opts map[string]string) (slideshowSettings, error) {
+	settings := slideshowSettings{Frame: defaultFrameDuration, Size: defaultSlideshowSize, Order: orderPlaylist}
+
+	if value, ok := opts["frame"]; ok {
+		frame, err := parseSeconds(value)
+		if err != nil || frame < minFrameDuration || frame > maxFrameDuration {
+			return settings, fmt.Errorf("unknown frame duration %q, use e.g. frame=0.5s or frame=300ms, from %s to %s",
+				value, minFrameDuration, maxFrameDuration)
+		}
+		settings.Frame = frame
+	}
+
+	if value, ok := opts["length"]; ok {
+		length, err := parseSeconds(value)
+		if err != nil || length < time.Second || length > maxSlideshowLength {
+			return settings, fmt.Errorf("unknown length %q, use e.g. length=10s, up to %s", value, maxSlideshowLength)
+		}
+		settings.Length = length
+	}
+
+	if value, ok := opts["size"]; ok {
+		size, err := strconv.Atoi(strings.TrimSuffix(value, "px"))
+		if err != nil || size < minSlideshowSize || size > maxSlideshowSize {
+			return settings, fmt.Errorf("unknown size %q, use a size from %d to %d pixels", value, minSlideshowSize, maxSlideshowSize)
+		}
+		settings.Size = size
+	}
+
+	if value, ok := opts["order"]; ok {
+		order, err := parseCoverOrder(value)
+		if err != nil {
+			return settings, err
+		}
+		settings.Order = order
+	}
+
+	return settings, nil
+}
+
+// parseSeconds parses a Go duration such as "300ms", or a plain number of seconds.
+func parseSeconds(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(value)
+}
+
+// slideshowBuilder keeps the downloaded covers of a job, up to
+// maxSlideshowCovers encoded images, and renders them into an animated GIF
+// at the end.
+type slideshowBuilder struct {
+	sender   *Sender
+	chatID   int64
+	settings slideshowSettings
+	onSent   func(count int)
+
+	covers  []*spotify.ImageData
+	dropped int
+}
+
+func newSlideshowBuilder(sender *Sender, chatID int64, settings slideshowSettings, onSent func(count int)) *slideshowBuilder {
+	return &slideshowBuilder{
+		sender:   sender,
+		chatID:   chatID,
+		settings: settings,
+		onSent:   onSent,
+	}
+}
+
+// Add keeps the cover for the slideshow.
+func (b *slideshowBuilder) Add(img *spotify.ImageData) error {
+	if len(img.Data) == 0 {
+		return nil
+	}
+	if len(b.covers) >= maxSlideshowCovers {
+		b.dropped++
+		return nil
+	}
+	b.covers = append(b.covers, img)
+	return nil
+}
+
+// Finish renders the slideshow and sends it, split into parts when the GIF
+// is over the file size limit.
+func (b *slideshowBuilder) Finish() error {
+	if len(b.covers) == 0 {
+		return nil
+	}
+	if b.dropped > 0 {
+		log.Warn().Int("dropped", b.dropped).Int("kept", len(b.covers)).Msg("Too many covers for a slideshow")
+	}
+
+	covers := sampleCovers(orderCovers(b.covers, b.settings.Order), b.maxFrames())
+	frame := b.settings.Frame
+	if b.settings.Length > 0 {
+		frame = max(b.settings.Length/time.Duration(len(covers)), minFrameDuration)
+	}
+
+	data := make([][]byte, len(covers))
+	for i, img := range covers {
+		data[i] = img.Data
+	}
+	started := time.Now()
+	slideshow := imaging.Slideshow(data, b.settings.Size, frame)
+	if len(slideshow.Image) == 0 {
+		return fmt.Errorf("no cover could be decoded for the slideshow")
+	}
+
+	parts, err := b.encode(slideshow)
+	if err != nil {
+		return err
+	}
+	log.Debug().
+		Int64("chat_id", b.chatID).
+		Int("frames", len(slideshow.Image)).
+		Int("covers", len(b.covers)).
+		Int("parts", len(parts)).
+		Dur("frame", frame).
+		Dur("took", time.Since(started)).
+		Msg("Rendered slideshow")
+
+	name := "spotify-covers-" + time.Now().Format("2006-01-02-150405")
+	for i, part := range parts {
+		fileName := name + ".gif"
+		caption := fmt.Sprintf("🎞 %d covers, %s per cover", part.frames, frame.Round(10*time.Millisecond))
+		if len(parts) > 1 {
+			fileName = fmt.Sprintf("%s-part%d.gif", name, i+1)
+			caption = fmt.Sprintf("🎞 %d/%d · %d covers, %s per cover", i+1, len(parts), part.frames, frame.Round(10*time.Millisecond))
+		}
+		if total := len(b.covers) + b.dropped; len(covers) < total && i == len(parts)-1 {
+			caption += fmt.Sprintf("\n%d of %d covers shown", len(covers), total)
+		}
+		if err := b.sender.SendAnimation(b.chatID, part.data, fileName, b.settings.Size, b.settings.Size, caption); err != nil {
+			return err
+		}
+		if b.onSent != nil {
+			b.onSent(part.frames)
+		}
+	}
+	return nil
+}
+
+// maxFrames limits the number of frames, so a slideshow with a set length
+// doesn't go below the shortest frame duration.
+func (b *slideshowBuilder) maxFrames() int {
+	if b.settings.Length > 0 {
+		return max(min(int(b.settings.Length/minFrameDuration), maxSlideshowFrames), 1)
+	}
+	return maxSlideshowFrames
+}
+
+type slideshowPart struct {
+	data   []byte
+	frames int
+}
+
+// encode encodes the GIF, halving the frames of any part that is over the
+// file size limit.
+func (b *slideshowBuilder) encode(g *gif.GIF) ([]slideshowPart, error) {
+	data, err := imaging.EncodeGIF(g)
+	if err != nil {
+		return nil, err
+	}
+	limit := int64(b.sender.maxFileSizeMB)*1024*1024 - slideshowSizeReserve
+	if int64(len(data)) <= limit || len(g.Image) < 2 {
+		return []slideshowPart{{data: data, frames: len(g.Image)}}, nil
+	}
+
+	half := len(g.Image) / 2
+	first, err := b.encode(subGIF(g, 0, half))
+	if err != nil {
+		return nil, err
+	}
+	second, err := b.encode(subGIF(g, half, len(g.Image)))
+	if err != nil {
+		return nil, err
+	}
+	return append(first, second...), nil
+}
+
+func subGIF(g *gif.GIF, from, to int) *gif.GIF {
+	return &gif.GIF{
+		Image:     g.Image[from:to],
+		Delay:     g.Delay[from:to],
+		Disposal:  g.Disposal[from:to],
+		LoopCount: g.LoopCount,
+	}
+}
+
+// sampleCovers picks up to n covers spread evenly over the list.
+func sampleCovers(covers []*spotify.ImageData, n int) []*spotify.ImageData {
+	if len(covers) <= n {
+		return covers
+	}
+	sampled := make([]*spotify.ImageData, n)
+	for i := range sampled {
+		sampled[i] = covers[i*len(covers)/n]
+	}
+	return sampled
+}
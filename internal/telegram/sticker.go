@@ -0,0 +1,190 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"image2spotify/internal/imaging"
+	"image2spotify/internal/spotify"
+	"image2spotify/internal/tmpl"
+
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v4"
+)
+
+// Telegram sticker set limits
+const (
+	maxStickers         = 120
+	maxStickerSetTitle  = 64
+	maxStickerKeyword   = 64
+	stickerEmoji        = "🎵"
+	stickerSetURLPrefix = "https://t.me/addstickers/"
+)
+
+// CreateStickerSet creates a sticker set owned by the user with its first
+// sticker, a PNG from imaging.Sticker. The set belongs to the primary bot, so
+// its name ends with "_by_<bot username>".
+func (s *Sender) CreateStickerSet(userID int64, name, title string, data []byte, keywords []string) error {
+	return s.retryFloodWait("sticker set create", maxSendRetries, func() error {
+		s.waitPrimary()
+		return s.primaryBot.CreateStickerSet(&tele.User{ID: userID}, &tele.StickerSet{
+			Type:  tele.StickerRegular,
+			Name:  name,
+			Title: title,
+			Input: []tele.InputSticker{inputSticker(data, keywords)},
+		})
+	})
+}
+
+// AddSticker adds a sticker to a set created by CreateStickerSet.
+func (s *Sender) AddSticker(userID int64, name string, data []byte, keywords []string) error {
+	return s.retryFloodWait("sticker add", maxSendRetries, func() error {
+		s.waitPrimary()
+		return s.primaryBot.AddStickerToSet(&tele.User{ID: userID}, name, inputSticker(data, keywords))
+	})
+}
+
+// inputSticker is built again for every attempt: the PNG reader is used up
+// by the first upload.
+func inputSticker(data []byte, keywords []string) tele.InputSticker {
+	return tele.InputSticker{
+		File:     tele.FromReader(bytes.NewReader(data)),
+		Format:   tele.StickerStatic,
+		Emojis:   []string{stickerEmoji},
+		Keywords: keywords,
+	}
+}
+
+// StickerSetName returns a new sticker set name for the user.
+func (s *Sender) StickerSetName(userID int64) string {
+	return fmt.Sprintf("covers_%s_%s_by_%s",
+		strconv.FormatInt(userID, 36), strconv.FormatInt(time.Now().Unix(), 36), s.primaryBot.Me.Username)
+}
+
+// stickerPackBuilder turns covers into stickers and adds them to a new set
+// owned by the requesting user. The set is created with the first cover.
+type stickerPackBuilder struct {
+	sender *Sender
+	userID int64
+	kind   spotify.LinkKind
+	onSent func(count int)
+
+	name    string
+	created bool
+	err     error // set creation failed, the rest of the covers are skipped
+	queued  int32 // covers let through to download by Accept
+	dropped int32 // covers over maxStickers, not downloaded
+}
+
+func newStickerPackBuilder(sender *Sender, userID int64, kind spotify.LinkKind, onSent func(count int)) *stickerPackBuilder {
+	return &stickerPackBuilder{
+		sender: sender,
+		userID: userID,
+		kind:   kind,
+		onSent: onSent,
+		name:   sender.StickerSetName(userID),
+	}
+}
+
+// Accept is called by the processor before a cover is downloaded. Once
+// maxStickers covers are queued, the rest are counted and not downloaded.
+func (b *stickerPackBuilder) Accept() bool {
+	if atomic.AddInt32(&b.queued, 1) > maxStickers {
+		atomic.AddInt32(&b.dropped, 1)
+		return false
+	}
+	return true
+}
+
+// Add converts the cover into a sticker and adds it to the set.
+func (b *stickerPackBuilder) Add(img *spotify.ImageData) error {
+	if len(img.Data) == 0 || b.err != nil {
+		return nil
+	}
+
+	decoded, err := imaging.Decode(img.Data)
+	if err != nil {
+		return err
+	}
+	data, err := imaging.Sticker(decoded)
+	if err != nil {
+		return err
+	}
+	keywords := stickerKeywords(img)
+
+	if !b.created {
+		if err := b.sender.CreateStickerSet(b.userID, b.name, b.title(img), data, keywords); err != nil {
+			b.err = err
+			return err
+		}
+		b.created = true
+		log.Info().Int64("user_id", b.userID).Str("name", b.name).Msg("Sticker set created")
+	} else if err := b.sender.AddSticker(b.userID, b.name, data, keywords); err != nil {
+		return err
+	}
+
+	if b.onSent != nil {
+		b.onSent(1)
+	}
+	return nil
+}
+
+// Result returns the link to the set and the notes for the final message.
+func (b *stickerPackBuilder) Result() (string, []string) {
+	var details []string
+	if b.err != nil {
+		details = append(details, "⚠️ Failed to create the sticker pack: "+stickerErrorMessage(b.err))
+	}
+	if dropped := atomic.LoadInt32(&b.dropped); dropped > 0 {
+		details = append(details, fmt.Sprintf("⚠️ %d covers didn't fit, a sticker pack holds up to %d stickers", dropped, maxStickers))
+	}
+	if !b.created {
+		return "", details
+	}
+	return stickerSetURLPrefix + b.name, details
+}
+
+// title names the set after the album or the artist, depending on the link.
+func (b *stickerPackBuilder) title(img *spotify.ImageData) string {
+	title := "Spotify covers"
+	switch {
+	case b.kind == spotify.KindArtist && len(img.Artists) > 0:
+		title = img.Artists[0].Name + " covers"
+	case (b.kind == spotify.KindAlbum || b.kind == spotify.KindTrack || b.kind == spotify.KindShow) && img.Album.Name != "":
+		title = img.Album.Name
+	case b.kind == spotify.KindPlaylist:
+		title = "Playlist covers"
+	}
+	return tmpl.Truncate(title, maxStickerSetTitle)
+}
+
+// stickerKeywords lets the stickers be found by album and artist names.
+func stickerKeywords(img *spotify.ImageData) []string {
+	var keywords []string
+	if img.Album.Name != "" {
+		keywords = append(keywords, tmpl.Truncate(img.Album.Name, maxStickerKeyword))
+	}
+	for _, artist := range img.Artists {
+		if len(keywords) >= 3 {
+			break
+		}
+		keywords = append(keywords, tmpl.Truncate(artist.Name, maxStickerKeyword))
+	}
+	return keywords
+}
+
+// stickerErrorMessage explains the common sticker set errors.
+func stickerErrorMessage(err error) string {
+	text := err.Error()
+	switch {
+	case strings.Contains(text, "PEER_ID_INVALID"), strings.Contains(text, "user not found"):
+		return "start a private chat with the bot first"
+	case strings.Contains(text, "STICKERSET_INVALID"), strings.Contains(text, "sticker set name"):
+		return "Telegram rejected the pack name, please try again"
+	}
+	return "please try again later"
+}
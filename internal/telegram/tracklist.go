@@ -0,0 +1,206 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"image2spotify/internal/imaging"
+	"image2spotify/internal/spotify"
+	"image2spotify/internal/tmpl"
+
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v4"
+)
+
+// Tracklist poster limits. Telegram shrinks photos to 2560 pixels on the
+// longer side, so taller posters are sent as files to keep the text sharp.
+const (
+	maxPosterTracks  = 100
+	maxPosterPhotoPx = 2560
+)
+
+const tracklistUsage = "Send /tracklist followed by an album or playlist link, e.g.\n" +
+	"/tracklist https://open.spotify.com/album/...\n\n" +
+	"You'll get a poster with the cover, the title and every track with its length."
+
+// HandleTracklist renders a poster with the cover and the tracklist of an
+// album or playlist.
+func (h *Handlers) HandleTracklist(c tele.Context) error {
+	payload := c.Message().Payload
+	rawLinks := spotify.FindLinks(payload)
+	if len(rawLinks) == 0 {
+		return c.Send(tracklistUsage)
+	}
+	fetchOpts, err := fetchOptions(parseRequestOptions(payload))
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	client := h.processor.GetSpotifyClient()
+	link, err := client.ParseLink(ctx, rawLinks[0])
+	if err != nil {
+		return c.Send("Unsupported Spotify link. " + tracklistUsage)
+	}
+	if link.Kind != spotify.KindAlbum && link.Kind != spotify.KindPlaylist {
+		return c.Send("Tracklist posters are made for albums and playlists, please send an album or playlist link.")
+	}
+
+	processingMsg, _ := c.Bot().Send(c.Sender(), "🧾 Rendering tracklist...")
+	fail := func(text string) error {
+		if processingMsg != nil {
+			c.Bot().Edit(processingMsg, "❌ "+text)
+			return nil
+		}
+		return c.Send("❌ " + text)
+	}
+
+	var list imaging.Tracklist
+	var images []spotify.Image
+	var tracks []spotify.Track
+	if link.Kind == spotify.KindAlbum {
+		tracks, err = client.GetAlbumTracks(ctx, link.ID, fetchOpts.Market)
+		if len(tracks) > 0 {
+			album := tracks[0].Album
+			images = album.Images
+			list.Title = album.Name
+			list.Subtitle = artistNames(tracks[0].CoverArtists())
+			list.Details = tracklistDetails(album.AlbumType, album.Year(), tracks)
+		}
+	} else {
+		var playlist *spotify.Playlist
+		if playlist, err = client.GetPlaylist(ctx, link.ID, fetchOpts.Market); err == nil {
+			images = playlist.Images
+			list.Title = playlist.Name
+			if playlist.Owner.DisplayName != "" {
+				list.Subtitle = "by " + playlist.Owner.DisplayName
+			}
+			tracks, err = client.GetPlaylistTracks(ctx, link.ID, fetchOpts.Market)
+			list.Details = tracklistDetails("playlist", "", tracks)
+		}
+	}
+	// A partly read listing is still drawn, with a warning in the caption:
+	// its track count and total length are short
+	partial, isPartial := spotify.AsPartialError(err)
+	if err != nil && (!isPartial || len(tracks) == 0) {
+		log.Error().Err(err).Str("url", link.Raw).Msg("Failed to get tracklist")
+		return fail(userErrorMessage(err))
+	}
+	if len(tracks) == 0 {
+		return fail("This " + string(link.Kind) + " has no tracks.")
+	}
+
+	albumArtists := artistNames(tracks[0].CoverArtists())
+	for i, track := range tracks {
+		if i == maxPosterTracks {
+			list.More = len(tracks) - maxPosterTracks
+			break
+		}
+		row := imaging.PosterTrack{Number: i + 1, Title: track.Name, Duration: track.Duration()}
+		artists := track.Artists
+		if len(artists) == 0 {
+			artists = track.CoverArtists()
+		}
+		// Album rows only name artists other than the album artists
+		if names := artistNames(artists); link.Kind == spotify.KindPlaylist || names != albumArtists {
+			row.Artists = names
+		}
+		list.Tracks = append(list.Tracks, row)
+	}
+
+	var cover *image.RGBA
+	if len(images) > 0 {
+		imageURL, fallbackURL := spotify.PickImage(images, spotify.ResolutionLargest)
+		data, downloadErr := h.processor.DownloadImage(ctx, imageURL, fallbackURL)
+		if downloadErr != nil {
+			log.Warn().Err(downloadErr).Str("url", imageURL).Msg("Failed to download cover for tracklist, rendering without it")
+		} else if cover, err = imaging.Decode(data); err != nil {
+			log.Warn().Err(err).Str("url", imageURL).Msg("Failed to decode cover for tracklist, rendering without it")
+			cover = nil
+		}
+	}
+
+	rendered, err := imaging.TracklistPoster(cover, list, imaging.PosterWidth)
+	var poster []byte
+	if err == nil {
+		poster, err = imaging.EncodeJPEG(rendered, 92)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("url", link.Raw).Msg("Failed to render tracklist")
+		return fail("Failed to render the tracklist.")
+	}
+
+	caption := fmt.Sprintf("🧾 %s · %s", tmpl.Truncate(list.Title, 200), list.Details)
+	if isPartial {
+		log.Warn().Err(partial.Err).Str("url", link.Raw).Int("total", partial.Total).Int("fetched", partial.Fetched).Msg("Tracklist read partially")
+		caption += "\n" + partialReadNote(partial)
+	}
+	posterHeight := rendered.Rect.Dy()
+	if posterHeight <= maxPosterPhotoPx {
+		err = h.sender.SendPicture(c.Chat().ID, poster, caption)
+	} else {
+		fileName := tmpl.Filename("{album} (tracklist)", tmpl.Values{"album": list.Title}) + ".jpg"
+		err = h.sender.SendFile(c.Chat().ID, poster, fileName, caption)
+	}
+	if err != nil {
+		return fail("Failed to send the tracklist, please try again later.")
+	}
+
+	if processingMsg != nil {
+		c.Bot().Delete(processingMsg)
+	}
+	log.Info().
+		Int64("user_id", c.Sender().ID).
+		Str("url", link.Raw).
+		Int("tracks", len(tracks)).
+		Int("height", posterHeight).
+		Msg("Tracklist sent")
+	return nil
+}
+
+// tracklistDetails describes the release: type, year, track count and length.
+func tracklistDetails(kind, year string, tracks []spotify.Track) string {
+	var total time.Duration
+	for _, track := range tracks {
+		total += track.Duration()
+	}
+
+	var parts []string
+	if kind != "" {
+		parts = append(parts, strings.ToUpper(kind[:1])+kind[1:])
+	}
+	if year != "" {
+		parts = append(parts, year)
+	}
+	if len(tracks) == 1 {
+		parts = append(parts, "1 track")
+	} else {
+		parts = append(parts, fmt.Sprintf("%d tracks", len(tracks)))
+	}
+	if total > 0 {
+		parts = append(parts, formatLength(total))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatLength formats a total length as "48 min" or "3 h 12 min".
+func formatLength(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes < 60 {
+		return fmt.Sprintf("%d min", max(minutes, 1))
+	}
+	return fmt.Sprintf("%d h %d min", minutes/60, minutes%60)
+}
+
+func artistNames(artists []spotify.Artist) string {
+	names := make([]string, 0, len(artists))
+	for _, artist := range artists {
+		names = append(names, artist.Name)
+	}
+	return strings.Join(names, ", ")
+}
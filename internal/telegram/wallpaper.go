@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"image2spotify/internal/imaging"
+	"image2spotify/internal/spotify"
+	"image2spotify/internal/tmpl"
+
+	"github.com/rs/zerolog/log"
+	tele "gopkg.in/telebot.v4"
+)
+
+const wallpaperUsage = "Send /wallpaper followed by a track or album link, e.g.\n" +
+	"/wallpaper https://open.spotify.com/album/... desktop\n\n" +
+	"Presets: phone (1080x1920, default) or desktop (2560x1440).\n" +
+	"Backdrops: blur (default) or palette."
+
+var wallpaperPresets = map[string]image.Point{
+	"phone":   imaging.PhoneSize,
+	"mobile":  imaging.PhoneSize,
+	"desktop": imaging.DesktopSize,
+	"pc":      imaging.DesktopSize,
+}
+
+// wallpaperSettings are the options of one wallpaper request.
+type wallpaperSettings struct {
+	Preset   string
+	Size     image.Point
+	Backdrop string
+}
+
+// parseWallpaperSettings reads the preset and the backdrop, given as plain
+// words ("desktop palette") or as "preset=desktop backdrop=palette".
+func parseWallpaperSettings(payload string) (wallpaperSettings, error) {
+	settings := wallpaperSettings{Preset: "phone", Size: imaging.PhoneSize, Backdrop: imaging.BackdropBlur}
+	setPreset := func(value string) bool {
+		size, ok := wallpaperPresets[value]
+		if ok {
+			settings.Preset, settings.Size = value, size
+		}
+		return ok
+	}
+	setBackdrop := func(value string) bool {
+		ok := value == imaging.BackdropBlur || value == imaging.BackdropPalette
+		if ok {
+			settings.Backdrop = value
+		}
+		return ok
+	}
+
+	for _, word := range strings.Fields(strings.ToLower(payload)) {
+		if !strings.Contains(word, "=") && !setPreset(word) {
+			setBackdrop(word)
+		}
+	}
+
+	opts := parseRequestOptions(payload)
+	if value, ok := opts["preset"]; ok && !setPreset(strings.ToLower(value)) {
+		return settings, fmt.Errorf("unknown preset %q, use preset=phone or preset=desktop", value)
+	}
+	if value, ok := opts["backdrop"]; ok && !setBackdrop(strings.ToLower(value)) {
+		return settings, fmt.Errorf("unknown backdrop %q, use backdrop=blur or backdrop=palette", value)
+	}
+	return settings, nil
+}
+
+// HandleWallpaper renders a phone or desktop wallpaper from a track or album cover.
+func (h *Handlers) HandleWallpaper(c tele.Context) error {
+	payload := c.Message().Payload
+	rawLinks := spotify.FindLinks(payload)
+	if len(rawLinks) == 0 {
+		return c.Send(wallpaperUsage)
+	}
+
+	settings, err := parseWallpaperSettings(payload)
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+	preset, size, backdrop := settings.Preset, settings.Size, settings.Backdrop
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	link, err := h.processor.GetSpotifyClient().ParseLink(ctx, rawLinks[0])
+	if err != nil {
+		return c.Send("Unsupported Spotify link. " + wallpaperUsage)
+	}
+	switch link.Kind {
+	case spotify.KindTrack, spotify.KindAlbum, spotify.KindEpisode, spotify.KindShow:
+	default:
+		return c.Send("Wallpapers are made from one cover, so please send a track, album, episode or podcast link.")
+	}
+
+	processingMsg, _ := c.Bot().Send(c.Sender(), "🖼 Rendering wallpaper...")
+	fail := func(text string) error {
+		if processingMsg != nil {
+			c.Bot().Edit(processingMsg, "❌ "+text)
+			return nil
+		}
+		return c.Send("❌ " + text)
+	}
+
+	cover, err := h.processor.GetSpotifyClient().GetCover(ctx, link, "")
+	if err != nil {
+		log.Error().Err(err).Str("url", link.Raw).Msg("Failed to get cover for wallpaper")
+		return fail(userErrorMessage(err))
+	}
+	if len(cover.Album.Images) == 0 {
+		return fail("This link has no cover.")
+	}
+
+	// Обои больше 640px, поэтому берём оригинал обложки
+	imageURL, fallbackURL := spotify.PickImage(cover.Album.Images, spotify.ResolutionOriginal)
+	data, err := h.processor.DownloadImage(ctx, imageURL, fallbackURL)
+	if err != nil {
+		log.Error().Err(err).Str("url", imageURL).Msg("Failed to download cover for wallpaper")
+		return fail("Failed to download the cover, please try again later.")
+	}
+	decoded, err := imaging.Decode(data)
+	if err != nil {
+		return fail("Failed to read the cover image.")
+	}
+
+	wallpaper, err := imaging.EncodeJPEG(imaging.Wallpaper(decoded, size, backdrop), 92)
+	if err != nil {
+		return fail("Failed to render the wallpaper.")
+	}
+
+	img := &spotify.ImageData{Album: cover.Album, Artists: cover.CoverArtists()}
+	fileName := tmpl.Filename(fmt.Sprintf("{artist} - {album} (%s wallpaper)", preset), tmpl.CoverValues(img, 1, 1)) + ".jpg"
+	caption := fmt.Sprintf("🖼 %s wallpaper, %dx%d", cover.Album.Name, size.X, size.Y)
+	if err := h.sender.SendFile(c.Chat().ID, wallpaper, fileName, caption); err != nil {
+		return fail("Failed to send the wallpaper, please try again later.")
+	}
+
+	if processingMsg != nil {
+		c.Bot().Delete(processingMsg)
+	}
+	log.Info().
+		Int64("user_id", c.Sender().ID).
+		Str("url", link.Raw).
+		Str("preset", preset).
+		Str("backdrop", backdrop).
+		Msg("Wallpaper sent")
+	return nil
+}
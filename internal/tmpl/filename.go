@@ -0,0 +1,116 @@
+package tmpl
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"image2spotify/internal/spotify"
+)
+
+// DefaultFilenameTemplate names covers when no template is configured.
+const DefaultFilenameTemplate = "{artist} - {album} ({year})"
+
+// maxFilenameBytes keeps names, before the extension and the collision
+// suffix, well under the 255-byte limit of common file systems.
+const maxFilenameBytes = 150
+
+// Namer hands out unique, safe file names for the covers of one job.
+type Namer struct {
+	template string
+
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+func NewNamer(template string) *Namer {
+	if strings.TrimSpace(template) == "" {
+		template = DefaultFilenameTemplate
+	}
+	return &Namer{template: template, used: make(map[string]bool)}
+}
+
+// Name expands the template for the cover. The extension comes from the image
+// bytes, and a repeated name gets a " (2)", " (3)"... suffix.
+func (n *Namer) Name(img *spotify.ImageData, index, total int) string {
+	base := Filename(n.template, CoverValues(img, index, total))
+	if base == "" {
+		base = Filename("{track_id}", CoverValues(img, index, total))
+	}
+	if base == "" {
+		base = fmt.Sprintf("cover %d", index)
+	}
+	ext := ImageExtension(img.Data)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	name := base + ext
+	for i := 2; n.used[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	n.used[strings.ToLower(name)] = true
+	return name
+}
+
+// Filename expands a file name template and makes the result safe: path
+// separators and characters Windows rejects are replaced, brackets left
+// empty by missing values are dropped and the length is limited.
+// An image extension at the end of the template is removed.
+func Filename(template string, values Values) string {
+	template = strings.TrimSuffix(template, path.Ext(template)) + keepExt(path.Ext(template))
+	name := sanitize(Expand(template, values, nil))
+
+	for _, empty := range []string{"()", "[]", "{}"} {
+		name = strings.ReplaceAll(name, empty, "")
+	}
+	name = strings.Join(strings.Fields(name), " ")
+	name = strings.Trim(name, " .-_")
+
+	if len(name) > maxFilenameBytes {
+		cut := maxFilenameBytes
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = strings.TrimRight(name[:cut], " .-_")
+	}
+	return name
+}
+
+// ImageExtension picks the file extension from the image bytes.
+func ImageExtension(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	}
+	return ".jpg"
+}
+
+// keepExt drops image extensions, which are chosen from the bytes instead.
+func keepExt(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".jpg", ".jpeg", ".png", ".webp", ".gif":
+		return ""
+	}
+	return ext
+}
+
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		case unicode.IsControl(r):
+			return ' '
+		}
+		return r
+	}, value)
+}
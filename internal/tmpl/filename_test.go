@@ -0,0 +1,83 @@
+package tmpl
+
+import (
+	"strings"
+	"testing"
+
+	"image2spotify/internal/spotify"
+)
+
+func TestFilename(t *testing.T) {
+	values := Values{"artist": "AC/DC", "album": `Who: "Live"?`, "year": "", "index": "3"}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "unsafe characters", template: "{artist} - {album}", want: "AC_DC - Who_ _Live"},
+		{name: "empty brackets dropped", template: "{artist} ({year})", want: "AC_DC"},
+		{name: "image extension removed", template: "{index:02} {artist}.jpg", want: "03 AC_DC"},
+		{name: "other extension kept", template: "{artist}.txt", want: "AC_DC.txt"},
+		{name: "trimmed", template: " - {year} - ", want: ""},
+		{name: "path separators", template: "../{artist}", want: "AC_DC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Filename(tt.template, values); got != tt.want {
+				t.Errorf("Filename(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilenameLength(t *testing.T) {
+	name := Filename("{album}", Values{"album": strings.Repeat("Ж", 200)})
+	if len(name) > maxFilenameBytes {
+		t.Errorf("len = %d bytes, want at most %d", len(name), maxFilenameBytes)
+	}
+	if !strings.HasPrefix(name, "ЖЖ") || strings.ToValidUTF8(name, "") != name {
+		t.Errorf("name cut inside a character: %q", name)
+	}
+}
+
+func TestNamerCollisions(t *testing.T) {
+	cover := func(album, trackID string) *spotify.ImageData {
+		return &spotify.ImageData{
+			TrackID: trackID,
+			Data:    []byte("\xff\xd8\xff\xe0 jpeg"),
+			Album:   spotify.SimpleAlbum{Name: album},
+			Artists: []spotify.Artist{{Name: "Artist"}},
+		}
+	}
+
+	namer := NewNamer("{artist} - {album}")
+	got := []string{
+		namer.Name(cover("Same", "a"), 1, 4),
+		namer.Name(cover("Same", "b"), 2, 4),
+		namer.Name(cover("SAME", "c"), 3, 4),
+		namer.Name(cover("Other", "d"), 4, 4),
+	}
+	want := []string{"Artist - Same.jpg", "Artist - Same (2).jpg", "Artist - SAME (3).jpg", "Artist - Other.jpg"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("name %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestNamerFallbacks(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+
+	namer := NewNamer("{album}")
+	if got := namer.Name(&spotify.ImageData{TrackID: "4uLU6hMCjMI75M1A2tKUQC", Data: png}, 1, 1); got != "4uLU6hMCjMI75M1A2tKUQC.png" {
+		t.Errorf("empty name: got %q, want the track ID", got)
+	}
+	if got := namer.Name(&spotify.ImageData{Data: png}, 2, 2); got != "cover 2.png" {
+		t.Errorf("no track ID: got %q, want the index", got)
+	}
+	if got := NewNamer("  ").Name(&spotify.ImageData{Album: spotify.SimpleAlbum{Name: "A"}, Artists: []spotify.Artist{{Name: "B"}}}, 1, 1); got != "B - A.jpg" {
+		t.Errorf("blank template: got %q, want the default template", got)
+	}
+}
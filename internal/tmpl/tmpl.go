@@ -0,0 +1,189 @@
+// Package tmpl expands the {placeholder} templates used for captions and filenames.
+package tmpl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"image2spotify/internal/spotify"
+)
+
+// Values maps placeholder names to their values.
+type Values map[string]string
+
+// CoverValues returns the placeholders available for a cover:
+// album, artists, artist, year, date, label, type, tracks, link, uri, upc,
+// track_id, index and total, plus color and palette (hex codes) once
+// img.Palette is filled.
+func CoverValues(img *spotify.ImageData, index, total int) Values {
+	album := img.Album
+
+	names := make([]string, 0, len(img.Artists))
+	for _, artist := range img.Artists {
+		names = append(names, artist.Name)
+	}
+	artist := ""
+	if len(names) > 0 {
+		artist = names[0]
+	}
+
+	link := album.ExternalURLs.Spotify
+	if link == "" && album.ID != "" {
+		kind := spotify.KindAlbum
+		if album.AlbumType == "show" {
+			kind = spotify.KindShow
+		}
+		link = spotify.Link{Kind: kind, ID: album.ID}.URL()
+	}
+
+	values := Values{
+		"album":    album.Name,
+		"artists":  strings.Join(names, ", "),
+		"artist":   artist,
+		"year":     album.Year(),
+		"date":     album.ReleaseDate,
+		"label":    album.Label,
+		"type":     album.AlbumType,
+		"link":     link,
+		"uri":      album.URI,
+		"upc":      album.ExternalIDs.UPC,
+		"track_id": img.TrackID,
+		"index":    strconv.Itoa(index),
+		"total":    strconv.Itoa(total),
+		"tracks":   "",
+	}
+	if album.TotalTracks > 0 {
+		values["tracks"] = strconv.Itoa(album.TotalTracks)
+	}
+	if len(img.Palette) > 0 {
+		hex := make([]string, len(img.Palette))
+		for i, c := range img.Palette {
+			hex[i] = fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+		}
+		values["color"] = hex[0]
+		values["palette"] = strings.Join(hex, " ")
+	}
+	return values
+}
+
+// Expand replaces {name} placeholders with escaped values. A numeric
+// placeholder may carry a width, {index:03} pads it with zeros to three digits.
+// Unknown placeholders are left as they are.
+func Expand(template string, values Values, escape func(string) string) string {
+	var b strings.Builder
+	rest := template
+
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(rest[:start])
+		name, format, _ := strings.Cut(rest[start+1:end], ":")
+		value, ok := values[name]
+		if !ok {
+			b.WriteString(rest[start : end+1])
+		} else {
+			value = pad(value, format)
+			if escape != nil {
+				value = escape(value)
+			}
+			b.WriteString(value)
+		}
+		rest = rest[end+1:]
+	}
+
+	b.WriteString(rest)
+	return b.String()
+}
+
+// unshortened are placeholders that ExpandLimited never cuts: a shortened
+// link would lead nowhere.
+var unshortened = map[string]bool{"link": true, "uri": true}
+
+// ExpandLimited expands the template so that the result, counted before
+// escaping, fits in limit characters. The longest values the template uses
+// are shortened first, so markup in the template stays intact. When that is
+// not enough, because the template itself is too long, the result is cut.
+func ExpandLimited(template string, values Values, escape func(string) string, limit int) string {
+	shortened := make(Values, len(values))
+	for name, value := range values {
+		shortened[name] = value
+	}
+	used := placeholders(template)
+
+	for {
+		over := utf8.RuneCountInString(Expand(template, shortened, nil)) - limit
+		if over <= 0 {
+			return Expand(template, shortened, escape)
+		}
+
+		longest, longestLen := "", 0
+		for name := range used {
+			if n := utf8.RuneCountInString(shortened[name]); !unshortened[name] && n > longestLen {
+				longest, longestLen = name, n
+			}
+		}
+		if longest == "" {
+			break
+		}
+		shortened[longest] = Truncate(shortened[longest], longestLen-over)
+	}
+
+	return Truncate(Expand(template, shortened, escape), limit)
+}
+
+// placeholders returns the names of the placeholders in the template.
+func placeholders(template string) map[string]bool {
+	names := make(map[string]bool)
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			return names
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return names
+		}
+		name, _, _ := strings.Cut(rest[start+1:start+end], ":")
+		names[name] = true
+		rest = rest[start+end+1:]
+	}
+}
+
+// Truncate cuts s to at most limit characters, ending it with "…" when cut.
+func Truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	if limit <= 1 {
+		return ""
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+// pad applies a zero-padding width such as "03" to a numeric value.
+func pad(value, format string) string {
+	if format == "" {
+		return value
+	}
+	width, err := strconv.Atoi(format)
+	number, numErr := strconv.Atoi(value)
+	if err != nil || numErr != nil {
+		return value
+	}
+	if strings.HasPrefix(format, "0") {
+		return fmt.Sprintf("%0*d", width, number)
+	}
+	return fmt.Sprintf("%*d", width, number)
+}
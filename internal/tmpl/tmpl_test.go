@@ -0,0 +1,112 @@
+package tmpl
+
+import (
+	"html"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestExpand(t *testing.T) {
+	values := Values{"album": "Rock & Roll", "artist": "AC/DC", "index": "7", "empty": ""}
+
+	tests := []struct {
+		name     string
+		template string
+		escape   func(string) string
+		want     string
+	}{
+		{name: "plain", template: "{artist} - {album}", want: "AC/DC - Rock & Roll"},
+		{name: "escaped values only", template: "<b>{album}</b>", escape: html.EscapeString, want: "<b>Rock &amp; Roll</b>"},
+		{name: "zero padding", template: "{index:03}.jpg", want: "007.jpg"},
+		{name: "space padding", template: "[{index:3}]", want: "[  7]"},
+		{name: "padding of text", template: "{album:03}", want: "Rock & Roll"},
+		{name: "unknown placeholder", template: "{album} {nope}", want: "Rock & Roll {nope}"},
+		{name: "empty value", template: "({empty})", want: "()"},
+		{name: "unclosed brace", template: "{album} {artist", want: "Rock & Roll {artist"},
+		{name: "no placeholders", template: "cover", want: "cover"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Expand(tt.template, values, tt.escape); got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		limit int
+		want  string
+	}{
+		{s: "short", limit: 10, want: "short"},
+		{s: "exactly", limit: 7, want: "exactly"},
+		{s: "too long text", limit: 8, want: "too lon…"},
+		{s: "word break", limit: 6, want: "word…"},
+		{s: "Время", limit: 3, want: "Вр…"},
+		{s: "abc", limit: 1, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.limit); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestExpandLimited(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	values := Values{
+		"album":   long,
+		"artists": "Artist",
+		"label":   strings.Repeat("unused ", 100),
+		"link":    "https://open.spotify.com/album/4aawyAB9vmqN3uQ7FjRGTy",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		limit    int
+		want     string
+	}{
+		{
+			name:     "fits",
+			template: "{album} by {artists}",
+			limit:    200,
+			want:     long + " by Artist",
+		},
+		{
+			name:     "longest used value shortened",
+			template: "{album} by {artists}",
+			limit:    30,
+			want:     strings.Repeat("a", 19) + "… by Artist",
+		},
+		{
+			name:     "link kept whole",
+			template: `<a href="{link}">{album}</a>` + strings.Repeat(".", 60),
+			limit:    140,
+			want:     `<a href="https://open.spotify.com/album/4aawyAB9vmqN3uQ7FjRGTy">` + strings.Repeat("a", 11) + "…</a>" + strings.Repeat(".", 60),
+		},
+		{
+			name:     "template over the limit",
+			template: strings.Repeat("x", 50) + " {album}",
+			limit:    40,
+			want:     strings.Repeat("x", 39) + "…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExpandLimited(tt.template, values, nil, tt.limit)
+			if got != tt.want {
+				t.Errorf("ExpandLimited =\n%q\nwant\n%q", got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.limit {
+				t.Errorf("result has %d characters, limit %d", n, tt.limit)
+			}
+		})
+	}
+}